@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"sort"
 	"strings"
 
 	"github.com/dominicgaliano/interpreter-demo/token"
@@ -44,16 +45,40 @@ type Expression interface {
 	expressionNode()
 }
 
+// Comments holds the comments attached to a statement. They are only filled
+// in by a parser attaching comments, for tools such as formatters, and are
+// not part of the statement's String.
+type Comments struct {
+	// LeadingComments appear on the lines before the statement.
+	LeadingComments []string
+	// TrailingComments appear after the statement starts, up to the end of
+	// its last line.
+	TrailingComments []string
+}
+
 // Program represents the root node of the AST
 type Program struct {
 	Statements []Statement
 }
 
+// String prints the program as source that parses back into an equivalent
+// program, though operators are fully parenthesized and formatting is lost.
 func (p *Program) String() string {
+	return joinStatements(p.Statements)
+}
+
+// joinStatements prints statements one after another. Let and return
+// statements end in a semicolon, expression statements need one added
+// before the next statement, so "a; -b" does not read as "a - b".
+func joinStatements(statements []Statement) string {
 	var out bytes.Buffer
 
-	for _, s := range p.Statements {
+	for i, s := range statements {
 		out.WriteString(s.String())
+
+		if _, ok := s.(*ExpressionStatement); ok && i < len(statements)-1 {
+			out.WriteString(";")
+		}
 	}
 
 	return out.String()
@@ -74,6 +99,7 @@ type LetStatement struct {
 	Token token.Token // the token.LET token
 	Name  *Identifier
 	Value Expression
+	Comments
 }
 
 func (ls *LetStatement) statementNode()       {}
@@ -94,6 +120,75 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// Pattern represents the left side of a destructuring let statement.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// DestructuringStatement represents a let statement binding several names
+// at once by unpacking an array or hash.
+// Ex. let [a, b] = [1, 2]; let {x, y} = point;
+type DestructuringStatement struct {
+	Token   token.Token // the token.LET token
+	Pattern Pattern
+	Value   Expression
+	Comments
+}
+
+func (ds *DestructuringStatement) statementNode()       {}
+func (ds *DestructuringStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DestructuringStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ds.TokenLiteral() + " ")
+	out.WriteString(ds.Pattern.String())
+	out.WriteString(" = ")
+
+	if ds.Value != nil {
+		out.WriteString(ds.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// ArrayPattern binds each name to the array element at the same position.
+// Ex. [first, second]
+type ArrayPattern struct {
+	Token    token.Token // the [ token
+	Elements []*Identifier
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	return "[" + joinIdentifiers(ap.Elements) + "]"
+}
+
+// HashPattern binds each name to the value stored under the string key of
+// the same name.
+// Ex. {x, y}
+type HashPattern struct {
+	Token token.Token // the { token
+	Keys  []*Identifier
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	return "{" + joinIdentifiers(hp.Keys) + "}"
+}
+
+func joinIdentifiers(identifiers []*Identifier) string {
+	names := []string{}
+	for _, ident := range identifiers {
+		names = append(names, ident.String())
+	}
+	return strings.Join(names, ", ")
+}
+
 // Identifier represent a variable identifier in the AST.
 // Ex. let x = 5; => Token = token.IDENT, Value = x
 // Identifiers can be used as expressions in some cases, so it implements the
@@ -112,6 +207,7 @@ func (i *Identifier) String() string       { return i.Value }
 type ReturnStatement struct {
 	Token       token.Token // the token.RETURN token
 	ReturnValue Expression
+	Comments
 }
 
 func (rs *ReturnStatement) statementNode()       {}
@@ -119,10 +215,10 @@ func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(rs.TokenLiteral() + " ")
+	out.WriteString(rs.TokenLiteral())
 
 	if rs.ReturnValue != nil {
-		out.WriteString(rs.ReturnValue.String())
+		out.WriteString(" " + rs.ReturnValue.String())
 	}
 
 	out.WriteString(";")
@@ -130,6 +226,22 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// WhileStatement represents a loop evaluating Body for as long as Condition
+// is truthy.
+// Ex. while (i < 10) { let i = i + 1; }
+type WhileStatement struct {
+	Token     token.Token // the token.WHILE token
+	Condition Expression
+	Body      *BlockStatement
+	Comments
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+	return "while (" + ws.Condition.String() + ") " + braced(ws.Body)
+}
+
 // ExpressionStatement represents an expression statement in the AST.
 // The expression is stored as a field and can be any valid expression.
 // ExpressionStatement is a wrapper around an expression that allows it to
@@ -138,6 +250,7 @@ func (rs *ReturnStatement) String() string {
 type ExpressionStatement struct {
 	Token      token.Token // the first token of the expression
 	Expression Expression
+	Comments
 }
 
 func (es *ExpressionStatement) statementNode()       {}
@@ -161,6 +274,16 @@ func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// FloatLiteral represents a floating point literal in the AST, ex. 1.5
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
 	Token    token.Token // the prefix token, ex. ! or -
 	Operator string
@@ -229,14 +352,14 @@ func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("if")
+	out.WriteString("if (")
 	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
-	out.WriteString(ie.Consequence.String())
+	out.WriteString(") ")
+	out.WriteString(braced(ie.Consequence))
 
 	if ie.Alternative != nil {
-		out.WriteString("else ")
-		out.WriteString(ie.Alternative.String())
+		out.WriteString(" else ")
+		out.WriteString(braced(ie.Alternative))
 	}
 
 	return out.String()
@@ -249,14 +372,19 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// String prints the statements of the block without the surrounding braces.
 func (bs *BlockStatement) String() string {
-	var out bytes.Buffer
+	return joinStatements(bs.Statements)
+}
 
-	for _, stmt := range bs.Statements {
-		out.WriteString(stmt.String())
+// braced prints a block with its braces, as it appears in if expressions and
+// function literals.
+func braced(bs *BlockStatement) string {
+	if len(bs.Statements) == 0 {
+		return "{}"
 	}
-
-	return out.String()
+	return "{ " + bs.String() + " }"
 }
 
 type FunctionLiteral struct {
@@ -278,7 +406,7 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
-	out.WriteString(fl.Body.String())
+	out.WriteString(braced(fl.Body))
 
 	return out.String()
 }
@@ -304,3 +432,142 @@ func (ce *CallExpression) String() string {
 
 	return out.String()
 }
+
+// StringLiteral represents a string literal in the AST.
+// The Value field holds the contents without the surrounding quotes.
+// Ex. "hello world" => Value = hello world
+type StringLiteral struct {
+	Token token.Token // the token.STRING token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string       { return `"` + sl.Value + `"` }
+
+// ArrayLiteral represents an array literal in the AST.
+// Each element can be any valid expression.
+// Ex. [1, 2 * 2, fn(x) { x }]
+type ArrayLiteral struct {
+	Token    token.Token // the [ token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// IndexExpression represents indexing into an array or hash in the AST.
+// Ex. myArray[1 + 1], myHash["key"]
+type IndexExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// HashLiteral represents a hash literal in the AST.
+// Both keys and values can be any valid expression, whether a key is usable
+// is only known once it has been evaluated.
+// Ex. {"name": "Monkey", 1 + 1: true}
+type HashLiteral struct {
+	Token token.Token // the { token
+	Pairs map[Expression]Expression
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for key, value := range hl.Pairs {
+		pairs = append(pairs, key.String()+": "+value.String())
+	}
+	// map iteration order is random, sorting keeps the output stable
+	sort.Strings(pairs)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// MatchArm is a single branch of a MatchExpression.
+// Ex. int => "an integer"
+type MatchArm struct {
+	Pattern *Identifier // the type name, or _ to match any type
+	Body    Expression
+}
+
+func (ma *MatchArm) String() string {
+	return ma.Pattern.String() + " => " + ma.Body.String()
+}
+
+// MatchExpression represents branching on the runtime type of a value.
+// The arms are tried in order and the body of the first arm naming the
+// subject's type is evaluated.
+// Ex. match x { int => "integer", string => "string", _ => "other" }
+type MatchExpression struct {
+	Token   token.Token // the token.MATCH token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// SpreadElement expands an array in place within call arguments or an array
+// literal. It is only valid as an element of those lists.
+// Ex. add(...pair), [0, ...rest, 9]
+type SpreadElement struct {
+	Token token.Token // the token.ELLIPSIS token
+	Right Expression
+}
+
+func (se *SpreadElement) expressionNode()      {}
+func (se *SpreadElement) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadElement) String() string       { return "..." + se.Right.String() }
@@ -27,3 +27,150 @@ func TestString(t *testing.T) {
 		t.Fatalf("program.String() wrong. Got=%q", program.String())
 	}
 }
+
+func TestInfixExpressionString(t *testing.T) {
+	ident := func(name string) *Identifier {
+		return &Identifier{
+			Token: token.Token{Type: token.IDENT, Literal: name},
+			Value: name,
+		}
+	}
+
+	// (a + b) * c
+	exp := &InfixExpression{
+		Token: token.Token{Type: token.ASTERISK, Literal: "*"},
+		Left: &InfixExpression{
+			Token:    token.Token{Type: token.PLUS, Literal: "+"},
+			Left:     ident("a"),
+			Operator: "+",
+			Right:    ident("b"),
+		},
+		Operator: "*",
+		Right:    ident("c"),
+	}
+
+	if exp.String() != "((a + b) * c)" {
+		t.Fatalf("exp.String() wrong. Got=%q", exp.String())
+	}
+	if exp.TokenLiteral() != "*" {
+		t.Fatalf("exp.TokenLiteral() wrong. Got=%q", exp.TokenLiteral())
+	}
+}
+
+func TestPrefixExpressionString(t *testing.T) {
+	tests := []struct {
+		exp      *PrefixExpression
+		expected string
+	}{
+		{
+			&PrefixExpression{
+				Token:    token.Token{Type: token.MINUS, Literal: "-"},
+				Operator: "-",
+				Right: &IntegerLiteral{
+					Token: token.Token{Type: token.INT, Literal: "5"},
+					Value: 5,
+				},
+			},
+			"(-5)",
+		},
+		{
+			&PrefixExpression{
+				Token:    token.Token{Type: token.BANG, Literal: "!"},
+				Operator: "!",
+				Right: &Boolean{
+					Token: token.Token{Type: token.TRUE, Literal: "true"},
+					Value: true,
+				},
+			},
+			"(!true)",
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.exp.String() != tt.expected {
+			t.Errorf("exp.String() wrong. Got=%q, want=%q", tt.exp.String(), tt.expected)
+		}
+	}
+}
+
+func TestBooleanString(t *testing.T) {
+	tests := []struct {
+		literal string
+		value   bool
+	}{
+		{"true", true},
+		{"false", false},
+	}
+
+	for _, tt := range tests {
+		b := &Boolean{
+			Token: token.Token{Type: token.LookupIdentifier(tt.literal), Literal: tt.literal},
+			Value: tt.value,
+		}
+
+		if b.String() != tt.literal {
+			t.Errorf("b.String() wrong. Got=%q, want=%q", b.String(), tt.literal)
+		}
+		if b.TokenLiteral() != tt.literal {
+			t.Errorf("b.TokenLiteral() wrong. Got=%q, want=%q", b.TokenLiteral(), tt.literal)
+		}
+	}
+}
+
+func TestHashLiteralString(t *testing.T) {
+	str := func(value string) *StringLiteral {
+		return &StringLiteral{
+			Token: token.Token{Type: token.STRING, Literal: value},
+			Value: value,
+		}
+	}
+	integer := func(literal string) *IntegerLiteral {
+		return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal}}
+	}
+
+	hash := &HashLiteral{
+		Token: token.Token{Type: token.LBRACE, Literal: "{"},
+		Pairs: map[Expression]Expression{
+			str("b"): integer("2"),
+			str("c"): integer("3"),
+			str("a"): integer("1"),
+		},
+	}
+
+	// map iteration order changes from run to run, the output must not
+	for i := 0; i < 10; i++ {
+		if hash.String() != `{"a": 1, "b": 2, "c": 3}` {
+			t.Fatalf("hash.String() wrong. Got=%q", hash.String())
+		}
+	}
+}
+
+func TestCanonicalStringCommentsAndNil(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let", Line: 2, Column: 1},
+				Name: &Identifier{
+					Token: token.Token{Type: token.IDENT, Literal: "x", Line: 2, Column: 5},
+					Value: "x",
+				},
+				Comments: Comments{
+					LeadingComments:  []string{"// leading"},
+					TrailingComments: []string{"// trailing"},
+				},
+			},
+		},
+	}
+
+	expected := `Program
+  Statements[0]: LetStatement @2:1
+    LeadingComments[0]: "// leading"
+    TrailingComments[0]: "// trailing"
+    Name: Identifier x @2:5
+    Value: nil
+`
+
+	if got := CanonicalString(program); got != expected {
+		t.Fatalf("CanonicalString wrong. got=\n%s\nwant=\n%s", got, expected)
+	}
+}
@@ -0,0 +1,210 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dominicgaliano/interpreter-demo/token"
+)
+
+// CanonicalString returns a dump of the program listing every node, one per
+// line, with its fields, literal values and line:column position. Children
+// are indented below their parent and labelled with the field holding them.
+// Unlike String, the output is fully explicit and deterministic, so it is
+// suitable for golden files compared with diff. Hash literal pairs are
+// sorted by the dump of their key and value, ignoring positions, as their map
+// has no order.
+func CanonicalString(program *Program) string {
+	c := &canonicalPrinter{}
+
+	c.line(0, "Program")
+	for i, stmt := range program.Statements {
+		c.node(1, fmt.Sprintf("Statements[%d]", i), stmt)
+	}
+
+	return c.out.String()
+}
+
+type canonicalPrinter struct {
+	out strings.Builder
+
+	// noPositions leaves out the line:column of each node
+	noPositions bool
+}
+
+func (c *canonicalPrinter) line(depth int, format string, args ...interface{}) {
+	c.out.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(&c.out, format, args...)
+	c.out.WriteByte('\n')
+}
+
+// header writes the line describing a node, detail is its literal value or
+// operator, if it has one.
+func (c *canonicalPrinter) header(depth int, field, name, detail string, tok token.Token) {
+	if detail != "" {
+		name += " " + detail
+	}
+	if c.noPositions {
+		c.line(depth, "%s: %s", field, name)
+		return
+	}
+	c.line(depth, "%s: %s @%d:%d", field, name, tok.Line, tok.Column)
+}
+
+func (c *canonicalPrinter) comments(depth int, comments Comments) {
+	for i, comment := range comments.LeadingComments {
+		c.line(depth, "LeadingComments[%d]: %q", i, comment)
+	}
+	for i, comment := range comments.TrailingComments {
+		c.line(depth, "TrailingComments[%d]: %q", i, comment)
+	}
+}
+
+func (c *canonicalPrinter) node(depth int, field string, node Node) {
+	// every node is a pointer, which may be nil within a non-nil interface
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		c.line(depth, "%s: nil", field)
+		return
+	}
+
+	switch node := node.(type) {
+	case *LetStatement:
+		c.header(depth, field, "LetStatement", "", node.Token)
+		c.comments(depth+1, node.Comments)
+		c.node(depth+1, "Name", node.Name)
+		c.node(depth+1, "Value", node.Value)
+	case *DestructuringStatement:
+		c.header(depth, field, "DestructuringStatement", "", node.Token)
+		c.comments(depth+1, node.Comments)
+		c.node(depth+1, "Pattern", node.Pattern)
+		c.node(depth+1, "Value", node.Value)
+	case *ArrayPattern:
+		c.header(depth, field, "ArrayPattern", "", node.Token)
+		for i, el := range node.Elements {
+			c.node(depth+1, fmt.Sprintf("Elements[%d]", i), el)
+		}
+	case *HashPattern:
+		c.header(depth, field, "HashPattern", "", node.Token)
+		for i, key := range node.Keys {
+			c.node(depth+1, fmt.Sprintf("Keys[%d]", i), key)
+		}
+	case *ReturnStatement:
+		c.header(depth, field, "ReturnStatement", "", node.Token)
+		c.comments(depth+1, node.Comments)
+		c.node(depth+1, "ReturnValue", node.ReturnValue)
+	case *WhileStatement:
+		c.header(depth, field, "WhileStatement", "", node.Token)
+		c.comments(depth+1, node.Comments)
+		c.node(depth+1, "Condition", node.Condition)
+		c.node(depth+1, "Body", node.Body)
+	case *ExpressionStatement:
+		c.header(depth, field, "ExpressionStatement", "", node.Token)
+		c.comments(depth+1, node.Comments)
+		c.node(depth+1, "Expression", node.Expression)
+	case *BlockStatement:
+		c.header(depth, field, "BlockStatement", "", node.Token)
+		for i, stmt := range node.Statements {
+			c.node(depth+1, fmt.Sprintf("Statements[%d]", i), stmt)
+		}
+	case *Identifier:
+		c.header(depth, field, "Identifier", node.Value, node.Token)
+	case *IntegerLiteral:
+		c.header(depth, field, "IntegerLiteral",
+			strconv.FormatInt(node.Value, 10), node.Token)
+	case *FloatLiteral:
+		c.header(depth, field, "FloatLiteral",
+			strconv.FormatFloat(node.Value, 'g', -1, 64), node.Token)
+	case *StringLiteral:
+		c.header(depth, field, "StringLiteral", strconv.Quote(node.Value), node.Token)
+	case *Boolean:
+		c.header(depth, field, "Boolean", strconv.FormatBool(node.Value), node.Token)
+	case *PrefixExpression:
+		c.header(depth, field, "PrefixExpression", node.Operator, node.Token)
+		c.node(depth+1, "Right", node.Right)
+	case *InfixExpression:
+		c.header(depth, field, "InfixExpression", node.Operator, node.Token)
+		c.node(depth+1, "Left", node.Left)
+		c.node(depth+1, "Right", node.Right)
+	case *IfExpression:
+		c.header(depth, field, "IfExpression", "", node.Token)
+		c.node(depth+1, "Condition", node.Condition)
+		c.node(depth+1, "Consequence", node.Consequence)
+		c.node(depth+1, "Alternative", node.Alternative)
+	case *FunctionLiteral:
+		c.header(depth, field, "FunctionLiteral", "", node.Token)
+		for i, param := range node.Parameters {
+			c.node(depth+1, fmt.Sprintf("Parameters[%d]", i), param)
+		}
+		c.node(depth+1, "Body", node.Body)
+	case *CallExpression:
+		c.header(depth, field, "CallExpression", "", node.Token)
+		c.node(depth+1, "Function", node.Function)
+		for i, arg := range node.Arguments {
+			c.node(depth+1, fmt.Sprintf("Arguments[%d]", i), arg)
+		}
+	case *ArrayLiteral:
+		c.header(depth, field, "ArrayLiteral", "", node.Token)
+		for i, el := range node.Elements {
+			c.node(depth+1, fmt.Sprintf("Elements[%d]", i), el)
+		}
+	case *IndexExpression:
+		c.header(depth, field, "IndexExpression", "", node.Token)
+		c.node(depth+1, "Left", node.Left)
+		c.node(depth+1, "Index", node.Index)
+	case *HashLiteral:
+		c.header(depth, field, "HashLiteral", "", node.Token)
+		c.hashPairs(depth+1, node.Pairs)
+	case *MatchExpression:
+		c.header(depth, field, "MatchExpression", "", node.Token)
+		c.node(depth+1, "Subject", node.Subject)
+		for i, arm := range node.Arms {
+			c.line(depth+1, "Arms[%d]: MatchArm", i)
+			c.node(depth+2, "Pattern", arm.Pattern)
+			c.node(depth+2, "Body", arm.Body)
+		}
+	case *SpreadElement:
+		c.header(depth, field, "SpreadElement", "", node.Token)
+		c.node(depth+1, "Right", node.Right)
+	default:
+		c.line(depth, "%s: %T %q", field, node, node.String())
+	}
+}
+
+// hashPairs writes the pairs of a hash literal sorted so the order is the same
+// on every run. Positions only break ties, so the order does not change when
+// the literal is reformatted.
+func (c *canonicalPrinter) hashPairs(depth int, pairs map[Expression]Expression) {
+	type pair struct {
+		order, key, value string
+	}
+
+	dumped := make([]pair, 0, len(pairs))
+	for key, value := range pairs {
+		order := &canonicalPrinter{noPositions: true}
+		order.node(0, "Key", key)
+		order.node(0, "Value", value)
+
+		k := &canonicalPrinter{noPositions: c.noPositions}
+		v := &canonicalPrinter{noPositions: c.noPositions}
+		k.node(depth+1, "Key", key)
+		v.node(depth+1, "Value", value)
+
+		dumped = append(dumped, pair{order.out.String(), k.out.String(), v.out.String()})
+	}
+
+	sort.Slice(dumped, func(i, j int) bool {
+		if dumped[i].order != dumped[j].order {
+			return dumped[i].order < dumped[j].order
+		}
+		return dumped[i].key+dumped[i].value < dumped[j].key+dumped[j].value
+	})
+
+	for i, p := range dumped {
+		c.line(depth, "Pairs[%d]:", i)
+		c.out.WriteString(p.key)
+		c.out.WriteString(p.value)
+	}
+}
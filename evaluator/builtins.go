@@ -0,0 +1,1634 @@
+package evaluator
+
+import (
+	"io"
+	"math"
+	"math/bits"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dominicgaliano/interpreter-demo/object"
+)
+
+// builtins maps the names of functions implemented natively in Go to their
+// object.Builtin wrappers. Identifiers are only looked up here when they are
+// not bound in the environment.
+var builtins map[string]*object.Builtin
+
+// The table is populated in init because higher-order builtins call back into
+// applyFunction, which would otherwise form an initialization cycle through
+// evalIdentifier.
+func init() {
+	builtins = map[string]*object.Builtin{
+		"len":     {Fn: builtinLen},
+		"reverse": {Fn: builtinReverse},
+		"partial": {Fn: builtinPartial},
+		"compose": {Fn: builtinCompose},
+		"times":   {Fn: builtinTimes},
+		"id":      {Fn: builtinId},
+		"type":    {Fn: builtinType},
+		"copy":    {Fn: builtinCopy},
+		"arity":   {Fn: builtinArity},
+		"curry":   {Fn: builtinCurry},
+		"tap":     {Fn: builtinTap},
+		"exit":    {Fn: builtinExit},
+		"puts":    {Fn: builtinPuts},
+
+		"has_key":    {Fn: builtinHasKey},
+		"has_value":  {Fn: builtinHasValue},
+		"set":        {Fn: builtinSet},
+		"get":        {Fn: builtinGet},
+		"key_string": {Fn: builtinKeyString},
+		"assoc_in":   {Fn: builtinAssocIn},
+		"get_in":     {Fn: builtinGetIn},
+
+		"first": {Fn: builtinFirst},
+		"last":  {Fn: builtinLast},
+		"rest":  {Fn: builtinRest},
+		"push":  {Fn: builtinPush},
+
+		"flatten":  {Fn: builtinFlatten},
+		"unique":   {Fn: builtinUnique},
+		"take":     {Fn: builtinTake},
+		"drop":     {Fn: builtinDrop},
+		"group_by": {Fn: builtinGroupBy},
+		"count":    {Fn: builtinCount},
+		"chunk":    {Fn: builtinChunk},
+		"every":    {Fn: builtinEvery},
+		"some":     {Fn: builtinSome},
+
+		"find":       {Fn: builtinFind},
+		"find_index": {Fn: builtinFindIndex},
+		"pad_left":   {Fn: builtinPadLeft},
+		"pad_right":  {Fn: builtinPadRight},
+		"repeat":     {Fn: builtinRepeat},
+
+		"starts_with": {Fn: builtinStartsWith},
+		"ends_with":   {Fn: builtinEndsWith},
+
+		"is_digit": {Fn: charClassBuiltin("is_digit", isDigitRune)},
+		"is_alpha": {Fn: charClassBuiltin("is_alpha", isAlphaRune)},
+		"is_space": {Fn: charClassBuiltin("is_space", isSpaceRune)},
+
+		"chars":      {Fn: builtinChars},
+		"from_chars": {Fn: builtinFromChars},
+		"to_int":     {Fn: builtinToInt},
+
+		"clamp": {Fn: builtinClamp},
+		"gcd":   {Fn: builtinGcd},
+		"lcm":   {Fn: builtinLcm},
+	}
+}
+
+// builtinLen returns the number of bytes in a string, elements in an array
+// or pairs in a hash.
+func builtinLen(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.String:
+		return &object.Integer{Value: int64(len(arg.Value))}
+	case *object.Array:
+		return &object.Integer{Value: int64(len(arg.Elements))}
+	case *object.Hash:
+		return &object.Integer{Value: int64(len(arg.Pairs))}
+	default:
+		return newError("argument to \"len\" not supported, got %s", args[0].Type())
+	}
+}
+
+// builtinReverse returns a new array or string with the elements in reverse
+// order. Strings are reversed by rune so multi-byte characters stay intact.
+// The argument is never mutated.
+func builtinReverse(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Array:
+		length := len(arg.Elements)
+		elements := make([]object.Object, length)
+		for i, el := range arg.Elements {
+			elements[length-1-i] = el
+		}
+		return &object.Array{Elements: elements}
+	case *object.String:
+		runes := []rune(arg.Value)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return &object.String{Value: string(runes)}
+	default:
+		return newError("argument to \"reverse\" must be ARRAY or STRING, got %s",
+			args[0].Type())
+	}
+}
+
+// builtinPartial binds the leading arguments of a function, returning a new
+// builtin that invokes the function with the bound arguments followed by the
+// arguments it is called with.
+func builtinPartial(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want at least 1",
+			len(args))
+	}
+
+	fn := args[0]
+	if !isCallable(fn) {
+		return newError("argument to \"partial\" must be FUNCTION, got %s",
+			fn.Type())
+	}
+
+	bound := append([]object.Object{}, args[1:]...)
+
+	return &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		combined := append(append([]object.Object{}, bound...), args...)
+
+		// applyFunction ignores surplus arguments, but passing more than the
+		// wrapped function accepts is almost certainly a mistake here
+		if function, ok := fn.(*object.Function); ok &&
+			len(combined) > len(function.Parameters) {
+			return newError("too many arguments to partial function. got=%d, want=%d",
+				len(combined), len(function.Parameters))
+		}
+
+		return applyFunction(fn, combined, env)
+	}}
+}
+
+// builtinCompose composes functions right-to-left, so compose(f, g)(x) is
+// equivalent to f(g(x)). The rightmost function receives every argument the
+// composition is called with; each later stage receives the previous result.
+func builtinCompose(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want at least 1",
+			len(args))
+	}
+
+	for _, fn := range args {
+		if !isCallable(fn) {
+			return newError("argument to \"compose\" must be FUNCTION, got %s",
+				fn.Type())
+		}
+	}
+
+	stages := append([]object.Object{}, args...)
+
+	return &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		result := applyFunction(stages[len(stages)-1], args, env)
+
+		for i := len(stages) - 2; i >= 0; i-- {
+			if stopsEvaluation(result) {
+				return result
+			}
+			result = applyFunction(stages[i], []object.Object{result}, env)
+		}
+
+		return result
+	}}
+}
+
+// maxTimesCount is the largest count times accepts, so a stray large count
+// is reported rather than running until memory is exhausted.
+const maxTimesCount = 1 << 24
+
+// builtinTimes calls fn(i) for each i in 0..n-1 and collects the results
+// into an array, stopping at the first error.
+func builtinTimes(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	count, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("first argument to \"times\" must be INTEGER, got %s",
+			args[0].Type())
+	}
+	if count.Value < 0 {
+		return newError("first argument to \"times\" must not be negative, got %d",
+			count.Value)
+	}
+	if count.Value > maxTimesCount {
+		return newError("first argument to \"times\" is too large, maximum is %d",
+			maxTimesCount)
+	}
+
+	fn := args[1]
+	if !isCallable(fn) {
+		return newError("second argument to \"times\" must be FUNCTION, got %s",
+			fn.Type())
+	}
+
+	results := []object.Object{}
+	for i := int64(0); i < count.Value; i++ {
+		result := applyFunction(fn, []object.Object{&object.Integer{Value: i}}, env)
+		if stopsEvaluation(result) {
+			return result
+		}
+		results = append(results, result)
+	}
+
+	return &object.Array{Elements: results}
+}
+
+// unidentifiedId is returned by id for values that are created afresh each
+// time they are computed, such as integers and strings, so their address
+// says nothing about aliasing.
+const unidentifiedId = -1
+
+// builtinId returns a stable identifier for its argument, its address in
+// memory. Two values share an id only if they are the same object, ex. the
+// TRUE/FALSE/NULL singletons or an array bound to two names.
+func builtinId(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch args[0].(type) {
+	case *object.Integer, *object.String:
+		return &object.Integer{Value: unidentifiedId}
+	default:
+		address := reflect.ValueOf(args[0]).Pointer()
+		return &object.Integer{Value: int64(address)}
+	}
+}
+
+// builtinTap calls fn(value) for its side effects and returns value itself,
+// so it can be dropped into a pipeline to observe it: x |> tap(log) |> f.
+func builtinTap(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	fn := args[1]
+	if !isCallable(fn) {
+		return newError("second argument to \"tap\" must be FUNCTION, got %s",
+			fn.Type())
+	}
+
+	if result := applyFunction(fn, []object.Object{args[0]}, env); stopsEvaluation(result) {
+		return result
+	}
+
+	return args[0]
+}
+
+// builtinPuts writes the Inspect of each argument on its own line to the
+// output of the evaluation, and returns NULL.
+func builtinPuts(env *object.Environment, args ...object.Object) object.Object {
+	out := output(env)
+	for _, arg := range args {
+		io.WriteString(out, arg.Inspect()+"\n")
+	}
+
+	return NULL
+}
+
+// unknownArity is the arity of builtins. They receive however many arguments
+// they are called with and validate the count themselves, so it is unknown.
+const unknownArity = -1
+
+// builtinArity returns the number of parameters a function declares, or
+// unknownArity for builtins, including those returned by partial and compose.
+func builtinArity(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch fn := args[0].(type) {
+	case *object.Function:
+		return &object.Integer{Value: int64(len(fn.Parameters))}
+	case *object.Builtin:
+		return &object.Integer{Value: unknownArity}
+	default:
+		return newError("argument to \"arity\" must be FUNCTION, got %s",
+			args[0].Type())
+	}
+}
+
+// builtinCurry returns a builtin that collects the arguments of fn over any
+// number of calls, one or several at a time, and calls fn once it has as many
+// as fn declares. Until then each call returns a builtin holding the
+// arguments gathered so far. Unlike partial, how the arguments are split
+// between calls is up to the caller. Builtins cannot be curried as their
+// arity is unknown.
+func builtinCurry(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch fn := args[0].(type) {
+	case *object.Function:
+		return curried(fn, nil)
+	case *object.Builtin:
+		return newError("cannot curry a builtin function, its arity is unknown")
+	default:
+		return newError("argument to \"curry\" must be FUNCTION, got %s",
+			args[0].Type())
+	}
+}
+
+func curried(fn *object.Function, bound []object.Object) *object.Builtin {
+	return &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		combined := append(append([]object.Object{}, bound...), args...)
+
+		arity := len(fn.Parameters)
+		switch {
+		case len(combined) > arity:
+			return newError("too many arguments to curried function. got=%d, want=%d",
+				len(combined), arity)
+		case len(combined) == arity:
+			return applyFunction(fn, combined, env)
+		default:
+			return curried(fn, combined)
+		}
+	}}
+}
+
+// builtinType returns the name of its argument's type, ex. "INTEGER".
+func builtinType(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	return &object.String{Value: TypeName(args[0])}
+}
+
+// TypeName returns the name of obj's type as reported by the type builtin.
+func TypeName(obj object.Object) string {
+	return string(obj.Type())
+}
+
+// builtinCopy returns a deep copy of its argument so the copy can be
+// modified without affecting the original.
+func builtinCopy(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	return deepCopy(args[0])
+}
+
+// deepCopy recursively copies arrays and hashes. All other objects are
+// returned as they are: scalars are never mutated in place and functions
+// keep sharing the environment they closed over.
+func deepCopy(obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.Array:
+		elements := make([]object.Object, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elements[i] = deepCopy(el)
+		}
+		return &object.Array{Elements: elements}
+	case *object.Hash:
+		pairs := make(map[object.HashKey]object.HashPair, len(obj.Pairs))
+		for key, pair := range obj.Pairs {
+			pairs[key] = object.HashPair{
+				Key:   deepCopy(pair.Key),
+				Value: deepCopy(pair.Value),
+			}
+		}
+		return &object.Hash{Pairs: pairs}
+	default:
+		return obj
+	}
+}
+
+// isCallable reports whether obj can be passed to applyFunction.
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin:
+		return true
+	default:
+		return false
+	}
+}
+
+// builtinExit stops the program with the given status code, 0 by default.
+// It does not exit the process, the host decides what to do with the code.
+func builtinExit(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+
+	if len(args) == 0 {
+		return &object.Exit{Code: 0}
+	}
+
+	code, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("argument to \"exit\" must be INTEGER, got %s", args[0].Type())
+	}
+
+	return &object.Exit{Code: code.Value}
+}
+
+// builtinHasKey reports whether the hash contains the key.
+func builtinHasKey(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("first argument to \"has_key\" must be HASH, got %s",
+			args[0].Type())
+	}
+
+	key, ok := object.HashKeyOf(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	_, ok = hash.Pairs[key]
+	return nativeBoolToBooleanObject(ok)
+}
+
+// builtinKeyString returns a readable string identifying a hashable value as
+// a hash key, ex. INTEGER:1 or STRING:"1". Unlike Inspect it includes the
+// type, so two values have the same key string only when they are the same
+// key.
+func builtinKeyString(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	if _, ok := object.HashKeyOf(args[0]); !ok {
+		return newError("unusable as hash key: %s", args[0].Type())
+	}
+
+	return &object.String{Value: keyString(args[0])}
+}
+
+// keyString formats a value already known to be hashable.
+func keyString(obj object.Object) string {
+	var value string
+
+	switch obj := obj.(type) {
+	case *object.String:
+		value = strconv.Quote(obj.Value)
+	case *object.Array:
+		elements := make([]string, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elements[i] = keyString(el)
+		}
+		value = "[" + strings.Join(elements, ", ") + "]"
+	default:
+		value = obj.Inspect()
+	}
+
+	return string(obj.Type()) + ":" + value
+}
+
+// builtinHasValue reports whether any key in the hash maps to a value equal
+// to the given one.
+func builtinHasValue(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("first argument to \"has_value\" must be HASH, got %s",
+			args[0].Type())
+	}
+
+	for _, pair := range hash.Pairs {
+		if objectsEqual(pair.Value, args[1]) {
+			return TRUE
+		}
+	}
+
+	return FALSE
+}
+
+// builtinSet returns a copy of the hash with the key set to the value. The
+// original hash is left unchanged.
+func builtinSet(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("first argument to \"set\" must be HASH, got %s",
+			args[0].Type())
+	}
+
+	key, ok := object.HashKeyOf(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	pairs := make(map[object.HashKey]object.HashPair, len(hash.Pairs)+1)
+	for k, pair := range hash.Pairs {
+		pairs[k] = pair
+	}
+	pairs[key] = object.HashPair{Key: args[1], Value: args[2]}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+// builtinAssocIn returns a copy of nested hashes and arrays with the value at
+// the path of keys and indexes replaced, ex. assoc_in(data, ["a", 0], 1).
+// Only the containers along the path are copied, the original is left
+// unchanged. Missing hash keys are added, with empty hashes created for the
+// rest of the path, but array indexes must be in range.
+func builtinAssocIn(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+
+	path, ok := args[1].(*object.Array)
+	if !ok {
+		return newError("second argument to \"assoc_in\" must be ARRAY, got %s",
+			args[1].Type())
+	}
+
+	return assocIn(args[0], path.Elements, args[2])
+}
+
+func assocIn(data object.Object, path []object.Object, value object.Object) object.Object {
+	if len(path) == 0 {
+		return value
+	}
+	step := path[0]
+
+	switch data := data.(type) {
+	case *object.Hash:
+		key, ok := object.HashKeyOf(step)
+		if !ok {
+			return newError("unusable as hash key: %s", step.Type())
+		}
+
+		var child object.Object = &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+		if pair, ok := data.Pairs[key]; ok {
+			child = pair.Value
+		}
+
+		updated := assocIn(child, path[1:], value)
+		if stopsEvaluation(updated) {
+			return updated
+		}
+
+		pairs := make(map[object.HashKey]object.HashPair, len(data.Pairs)+1)
+		for k, pair := range data.Pairs {
+			pairs[k] = pair
+		}
+		pairs[key] = object.HashPair{Key: step, Value: updated}
+
+		return &object.Hash{Pairs: pairs}
+	case *object.Array:
+		index, ok := step.(*object.Integer)
+		if !ok {
+			return newError("array index in path to \"assoc_in\" must be INTEGER, got %s",
+				step.Type())
+		}
+
+		idx, ok := resolveIndex(index.Value, len(data.Elements))
+		if !ok {
+			return newError("index out of range in path to \"assoc_in\": %d", index.Value)
+		}
+
+		updated := assocIn(data.Elements[idx], path[1:], value)
+		if stopsEvaluation(updated) {
+			return updated
+		}
+
+		elements := append([]object.Object{}, data.Elements...)
+		elements[idx] = updated
+
+		return &object.Array{Elements: elements}
+	default:
+		return newError("path to \"assoc_in\" cannot index into %s", data.Type())
+	}
+}
+
+// builtinGetIn walks the path of keys and indexes into nested hashes and
+// arrays and returns the value found, ex. get_in(data, ["a", 0]). When a step
+// is missing, out of range or cannot be indexed, it returns the default, or
+// NULL without one.
+func builtinGetIn(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+
+	path, ok := args[1].(*object.Array)
+	if !ok {
+		return newError("second argument to \"get_in\" must be ARRAY, got %s",
+			args[1].Type())
+	}
+
+	var fallback object.Object = NULL
+	if len(args) == 3 {
+		fallback = args[2]
+	}
+
+	current := args[0]
+	for _, step := range path.Elements {
+		next, ok := lookupStep(current, step)
+		if !ok {
+			return fallback
+		}
+		current = next
+	}
+
+	return current
+}
+
+// lookupStep indexes a hash or array the way an index expression would, and
+// reports whether a value was found.
+func lookupStep(data, step object.Object) (object.Object, bool) {
+	switch data := data.(type) {
+	case *object.Hash:
+		key, ok := object.HashKeyOf(step)
+		if !ok {
+			return nil, false
+		}
+		pair, ok := data.Pairs[key]
+		return pair.Value, ok
+	case *object.Array:
+		index, ok := step.(*object.Integer)
+		if !ok {
+			return nil, false
+		}
+		idx, ok := resolveIndex(index.Value, len(data.Elements))
+		if !ok {
+			return nil, false
+		}
+		return data.Elements[idx], true
+	default:
+		return nil, false
+	}
+}
+
+// builtinGet returns the value stored under the key, or the default when the
+// key is absent. Without a default, absent keys give NULL like indexing.
+func builtinGet(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("first argument to \"get\" must be HASH, got %s",
+			args[0].Type())
+	}
+
+	key, ok := object.HashKeyOf(args[1])
+	if !ok {
+		return newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	if pair, ok := hash.Pairs[key]; ok {
+		return pair.Value
+	}
+
+	if len(args) == 3 {
+		return args[2]
+	}
+	return NULL
+}
+
+// builtinFirst returns the first element of an array, or NULL when it is
+// empty.
+func builtinFirst(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to \"first\" must be ARRAY, got %s", args[0].Type())
+	}
+
+	if len(array.Elements) == 0 {
+		return NULL
+	}
+	return array.Elements[0]
+}
+
+// builtinLast returns the last element of an array, or NULL when it is
+// empty.
+func builtinLast(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to \"last\" must be ARRAY, got %s", args[0].Type())
+	}
+
+	if len(array.Elements) == 0 {
+		return NULL
+	}
+	return array.Elements[len(array.Elements)-1]
+}
+
+// builtinRest returns a new array holding every element but the first, or
+// NULL when the array is empty.
+func builtinRest(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to \"rest\" must be ARRAY, got %s", args[0].Type())
+	}
+
+	if len(array.Elements) == 0 {
+		return NULL
+	}
+	return &object.Array{Elements: append([]object.Object{}, array.Elements[1:]...)}
+}
+
+// builtinPush returns a new array with the value appended. The original
+// array is left unchanged.
+func builtinPush(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to \"push\" must be ARRAY, got %s",
+			args[0].Type())
+	}
+
+	elements := make([]object.Object, len(array.Elements), len(array.Elements)+1)
+	copy(elements, array.Elements)
+
+	return &object.Array{Elements: append(elements, args[1])}
+}
+
+// builtinFlatten splices nested arrays into a new, flat array. Without a
+// depth every level is flattened, flatten(arr, 1) only removes one level.
+func builtinFlatten(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to \"flatten\" must be ARRAY, got %s",
+			args[0].Type())
+	}
+
+	depth := int64(-1)
+	if len(args) == 2 {
+		d, ok := args[1].(*object.Integer)
+		if !ok {
+			return newError("second argument to \"flatten\" must be INTEGER, got %s",
+				args[1].Type())
+		}
+		if d.Value < 0 {
+			return newError("second argument to \"flatten\" must not be negative, got %d",
+				d.Value)
+		}
+		depth = d.Value
+	}
+
+	return &object.Array{Elements: flatten(array.Elements, depth)}
+}
+
+// flatten appends elements to a new slice, splicing in nested arrays up to
+// depth levels deep. A negative depth has no limit.
+func flatten(elements []object.Object, depth int64) []object.Object {
+	flat := []object.Object{}
+
+	for _, el := range elements {
+		nested, ok := el.(*object.Array)
+		if !ok || depth == 0 {
+			flat = append(flat, el)
+			continue
+		}
+		flat = append(flat, flatten(nested.Elements, depth-1)...)
+	}
+
+	return flat
+}
+
+// builtinUnique returns a new array with duplicate elements removed, keeping
+// the first occurrence of each. Hashable elements are compared by hash key
+// like hash keys are, elements that cannot be hash keys (functions, hashes
+// and arrays containing them) fall back to a linear objectsEqual scan.
+func builtinUnique(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to \"unique\" must be ARRAY, got %s",
+			args[0].Type())
+	}
+
+	seen := make(map[object.HashKey]bool)
+	unhashable := []object.Object{}
+	elements := []object.Object{}
+
+	for _, el := range array.Elements {
+		if key, ok := object.HashKeyOf(el); ok {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		} else {
+			if containsEqual(unhashable, el) {
+				continue
+			}
+			unhashable = append(unhashable, el)
+		}
+
+		elements = append(elements, el)
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// containsEqual reports whether any of elements is equal to obj.
+func containsEqual(elements []object.Object, obj object.Object) bool {
+	for _, el := range elements {
+		if objectsEqual(el, obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinTake returns a new array of the first n elements, or all of them
+// when there are fewer than n.
+func builtinTake(env *object.Environment, args ...object.Object) object.Object {
+	array, n, err := arrayAndCount("take", args)
+	if err != nil {
+		return err
+	}
+
+	return &object.Array{Elements: append([]object.Object{}, array.Elements[:n]...)}
+}
+
+// builtinDrop returns a new array of the elements after the first n, which
+// is empty when there are fewer than n.
+func builtinDrop(env *object.Environment, args ...object.Object) object.Object {
+	array, n, err := arrayAndCount("drop", args)
+	if err != nil {
+		return err
+	}
+
+	return &object.Array{Elements: append([]object.Object{}, array.Elements[n:]...)}
+}
+
+// builtinGroupBy calls fn on each element and returns a hash mapping every
+// key fn produced to an array of the elements that produced it, in order.
+func builtinGroupBy(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to \"group_by\" must be ARRAY, got %s",
+			args[0].Type())
+	}
+
+	fn := args[1]
+	if !isCallable(fn) {
+		return newError("second argument to \"group_by\" must be FUNCTION, got %s",
+			fn.Type())
+	}
+
+	pairs := make(map[object.HashKey]object.HashPair)
+	for _, el := range array.Elements {
+		key := applyFunction(fn, []object.Object{el}, env)
+		if stopsEvaluation(key) {
+			return key
+		}
+
+		hashKey, ok := object.HashKeyOf(key)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		pair, ok := pairs[hashKey]
+		if !ok {
+			pair = object.HashPair{Key: key, Value: &object.Array{}}
+		}
+		group := pair.Value.(*object.Array)
+		group.Elements = append(group.Elements, el)
+		pairs[hashKey] = pair
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+// builtinCount returns a hash mapping each distinct element to the number of
+// times it occurs. Given a predicate it instead returns how many elements
+// the predicate is truthy for.
+func builtinCount(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to \"count\" must be ARRAY, got %s",
+			args[0].Type())
+	}
+
+	if len(args) == 2 {
+		return countMatching(array, args[1], env)
+	}
+
+	pairs := make(map[object.HashKey]object.HashPair)
+	for _, el := range array.Elements {
+		key, ok := object.HashKeyOf(el)
+		if !ok {
+			return newError("unusable as hash key: %s", el.Type())
+		}
+
+		pair, ok := pairs[key]
+		if !ok {
+			pair = object.HashPair{Key: el, Value: &object.Integer{Value: 0}}
+		}
+		pair.Value = &object.Integer{Value: pair.Value.(*object.Integer).Value + 1}
+		pairs[key] = pair
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+// countMatching returns how many elements of array predicate is truthy for.
+func countMatching(
+	array *object.Array,
+	predicate object.Object,
+	env *object.Environment,
+) object.Object {
+	if !isCallable(predicate) {
+		return newError("second argument to \"count\" must be FUNCTION, got %s",
+			predicate.Type())
+	}
+
+	count := int64(0)
+	for _, el := range array.Elements {
+		result := applyFunction(predicate, []object.Object{el}, env)
+		if stopsEvaluation(result) {
+			return result
+		}
+		if isTruthy(result) {
+			count++
+		}
+	}
+
+	return &object.Integer{Value: count}
+}
+
+// builtinChunk splits an array into new arrays of size elements each. The
+// last chunk holds the remainder when the length is not a multiple of size.
+func builtinChunk(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to \"chunk\" must be ARRAY, got %s",
+			args[0].Type())
+	}
+
+	size, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to \"chunk\" must be INTEGER, got %s",
+			args[1].Type())
+	}
+	if size.Value <= 0 {
+		return newError("second argument to \"chunk\" must be positive, got %d",
+			size.Value)
+	}
+
+	chunks := []object.Object{}
+	for start := 0; start < len(array.Elements); start += int(size.Value) {
+		end := len(array.Elements)
+		if int64(end-start) > size.Value {
+			end = start + int(size.Value)
+		}
+
+		elements := append([]object.Object{}, array.Elements[start:end]...)
+		chunks = append(chunks, &object.Array{Elements: elements})
+	}
+
+	return &object.Array{Elements: chunks}
+}
+
+// builtinEvery reports whether the predicate is truthy for every element,
+// stopping at the first element it is not. It is TRUE for an empty array.
+func builtinEvery(env *object.Environment, args ...object.Object) object.Object {
+	return anyElement("every", args, false, env)
+}
+
+// builtinSome reports whether the predicate is truthy for any element,
+// stopping at the first element it is. It is FALSE for an empty array.
+func builtinSome(env *object.Environment, args ...object.Object) object.Object {
+	return anyElement("some", args, true, env)
+}
+
+// anyElement applies the predicate to each element of the array until it
+// returns a value whose truthiness is want, returning want if it did and
+// !want otherwise. every looks for a falsy result and some for a truthy one.
+func anyElement(
+	name string,
+	args []object.Object,
+	want bool,
+	env *object.Environment,
+) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to %q must be ARRAY, got %s",
+			name, args[0].Type())
+	}
+
+	predicate := args[1]
+	if !isCallable(predicate) {
+		return newError("second argument to %q must be FUNCTION, got %s",
+			name, predicate.Type())
+	}
+
+	for _, el := range array.Elements {
+		result := applyFunction(predicate, []object.Object{el}, env)
+		if stopsEvaluation(result) {
+			return result
+		}
+		if isTruthy(result) == want {
+			return nativeBoolToBooleanObject(want)
+		}
+	}
+
+	return nativeBoolToBooleanObject(!want)
+}
+
+// builtinFind returns the first element the predicate is truthy for, or NULL
+// if there is none.
+func builtinFind(env *object.Environment, args ...object.Object) object.Object {
+	index, err := findIndex("find", args, env)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		return NULL
+	}
+
+	return args[0].(*object.Array).Elements[index]
+}
+
+// builtinFindIndex returns the index of the first element the predicate is
+// truthy for, or -1 if there is none.
+func builtinFindIndex(env *object.Environment, args ...object.Object) object.Object {
+	index, err := findIndex("find_index", args, env)
+	if err != nil {
+		return err
+	}
+
+	return &object.Integer{Value: int64(index)}
+}
+
+// findIndex validates the (array, predicate) arguments of the builtin called
+// name and returns the index of the first element the predicate is truthy
+// for, -1 if there is none. An error from the predicate is returned as is.
+func findIndex(
+	name string,
+	args []object.Object,
+	env *object.Environment,
+) (int, object.Object) {
+	if len(args) != 2 {
+		return 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return 0, newError("first argument to %q must be ARRAY, got %s",
+			name, args[0].Type())
+	}
+
+	predicate := args[1]
+	if !isCallable(predicate) {
+		return 0, newError("second argument to %q must be FUNCTION, got %s",
+			name, predicate.Type())
+	}
+
+	for i, el := range array.Elements {
+		result := applyFunction(predicate, []object.Object{el}, env)
+		if stopsEvaluation(result) {
+			return 0, result
+		}
+		if isTruthy(result) {
+			return i, nil
+		}
+	}
+
+	return -1, nil
+}
+
+// builtinPadLeft pads the start of a string with the fill character, a space
+// by default, until it is at least width runes long.
+func builtinPadLeft(env *object.Environment, args ...object.Object) object.Object {
+	str, padding, err := stringPadding("pad_left", args)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: padding + str}
+}
+
+// builtinPadRight pads the end of a string with the fill character, a space
+// by default, until it is at least width runes long.
+func builtinPadRight(env *object.Environment, args ...object.Object) object.Object {
+	str, padding, err := stringPadding("pad_right", args)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: str + padding}
+}
+
+// stringPadding validates the (string, width, fill) arguments of the builtin
+// called name and returns the string along with the padding it needs.
+// Widths are counted in runes, so multi-byte characters count once.
+func stringPadding(name string, args []object.Object) (string, string, *object.Error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", "", newError("wrong number of arguments. got=%d, want=2 or 3",
+			len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return "", "", newError("first argument to %q must be STRING, got %s",
+			name, args[0].Type())
+	}
+
+	width, ok := args[1].(*object.Integer)
+	if !ok {
+		return "", "", newError("second argument to %q must be INTEGER, got %s",
+			name, args[1].Type())
+	}
+	if width.Value < 0 {
+		return "", "", newError("second argument to %q must not be negative, got %d",
+			name, width.Value)
+	}
+
+	fill := " "
+	if len(args) == 3 {
+		f, ok := args[2].(*object.String)
+		if !ok || utf8.RuneCountInString(f.Value) != 1 {
+			return "", "", newError("third argument to %q must be a single character, got %s",
+				name, args[2].Inspect())
+		}
+		fill = f.Value
+	}
+
+	missing := width.Value - int64(utf8.RuneCountInString(str.Value))
+	if missing <= 0 {
+		return str.Value, "", nil
+	}
+
+	// the same limit as repeat, compared by division so it cannot overflow
+	if missing > int64(maxRepeatLength/len(fill)) {
+		return "", "", newError("result of %q is too long, maximum length is %d",
+			name, maxRepeatLength)
+	}
+
+	return str.Value, strings.Repeat(fill, int(missing)), nil
+}
+
+// maxRepeatLength is the longest string, in bytes, repeat will build. It
+// stops a stray large count from allocating all available memory.
+const maxRepeatLength = 1 << 24
+
+// builtinRepeat returns the string repeated n times. Counts of zero or less
+// give the empty string.
+func builtinRepeat(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to \"repeat\" must be STRING, got %s",
+			args[0].Type())
+	}
+
+	count, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to \"repeat\" must be INTEGER, got %s",
+			args[1].Type())
+	}
+
+	if count.Value <= 0 || str.Value == "" {
+		return &object.String{Value: ""}
+	}
+
+	// compare by division so the product cannot overflow
+	if count.Value > int64(maxRepeatLength/len(str.Value)) {
+		return newError("result of \"repeat\" is too long, maximum length is %d",
+			maxRepeatLength)
+	}
+
+	return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+}
+
+// builtinStartsWith reports whether the string begins with the prefix.
+func builtinStartsWith(env *object.Environment, args ...object.Object) object.Object {
+	str, prefix, err := twoStrings("starts_with", args)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(strings.HasPrefix(str, prefix))
+}
+
+// builtinEndsWith reports whether the string ends with the suffix.
+func builtinEndsWith(env *object.Environment, args ...object.Object) object.Object {
+	str, suffix, err := twoStrings("ends_with", args)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(strings.HasSuffix(str, suffix))
+}
+
+// builtinChars splits a string into an array of single character strings,
+// one per rune.
+func builtinChars(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to \"chars\" must be STRING, got %s",
+			args[0].Type())
+	}
+
+	elements := []object.Object{}
+	for _, r := range str.Value {
+		elements = append(elements, &object.String{Value: string(r)})
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// builtinFromChars joins an array of single character strings into one
+// string, the inverse of chars.
+func builtinFromChars(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument to \"from_chars\" must be ARRAY, got %s",
+			args[0].Type())
+	}
+
+	var out strings.Builder
+	for i, el := range array.Elements {
+		char, ok := el.(*object.String)
+		if !ok || utf8.RuneCountInString(char.Value) != 1 {
+			return newError("element %d of \"from_chars\" argument must be a single character, got %s",
+				i, el.Inspect())
+		}
+		out.WriteString(char.Value)
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// builtinToInt parses a string as an integer in the given base, 10 by
+// default. A string that is not a valid integer gives NULL rather than an
+// error, so callers can branch on the result.
+func builtinToInt(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to \"to_int\" must be STRING, got %s",
+			args[0].Type())
+	}
+
+	base := int64(10)
+	if len(args) == 2 {
+		b, ok := args[1].(*object.Integer)
+		if !ok {
+			return newError("second argument to \"to_int\" must be INTEGER, got %s",
+				args[1].Type())
+		}
+		if b.Value < 2 || b.Value > 36 {
+			return newError("second argument to \"to_int\" must be between 2 and 36, got %d",
+				b.Value)
+		}
+		base = b.Value
+	}
+
+	value, err := strconv.ParseInt(str.Value, int(base), 64)
+	if err != nil {
+		return NULL
+	}
+
+	return &object.Integer{Value: value}
+}
+
+// builtinClamp limits a value to the range [low, high]. The result is an
+// integer when all three arguments are, otherwise every argument is
+// promoted and the result is a float.
+func builtinClamp(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+
+	allIntegers := true
+	for _, arg := range args {
+		if !isNumber(arg) {
+			return newError("arguments to \"clamp\" must be INTEGER or FLOAT, got %s",
+				arg.Type())
+		}
+		if arg.Type() != object.INTEGER_OBJ {
+			allIntegers = false
+		}
+	}
+
+	// compare integers as they are, large ones lose precision as floats
+	if allIntegers {
+		value := args[0].(*object.Integer).Value
+		low := args[1].(*object.Integer).Value
+		high := args[2].(*object.Integer).Value
+
+		switch {
+		case low > high:
+			return clampBoundsError(args)
+		case value < low:
+			return args[1]
+		case value > high:
+			return args[2]
+		default:
+			return args[0]
+		}
+	}
+
+	value, low, high := toFloat(args[0]), toFloat(args[1]), toFloat(args[2])
+
+	switch {
+	case low > high:
+		return clampBoundsError(args)
+	case value < low:
+		return &object.Float{Value: low}
+	case value > high:
+		return &object.Float{Value: high}
+	default:
+		return &object.Float{Value: value}
+	}
+}
+
+func clampBoundsError(args []object.Object) *object.Error {
+	return newError("lower bound of \"clamp\" is greater than upper bound, %s > %s",
+		args[1].Inspect(), args[2].Inspect())
+}
+
+// builtinGcd returns the greatest common divisor of two integers, which is
+// never negative. gcd(0, 0) is 0.
+func builtinGcd(env *object.Environment, args ...object.Object) object.Object {
+	a, b, err := twoIntegers("gcd", args)
+	if err != nil {
+		return err
+	}
+
+	g := gcd(absUint(a), absUint(b))
+	if g > math.MaxInt64 {
+		return newError("integer overflow in \"gcd\"")
+	}
+
+	return &object.Integer{Value: int64(g)}
+}
+
+// builtinLcm returns the least common multiple of two integers, which is
+// never negative. It is 0 if either integer is 0. There are no big integers,
+// so a result that does not fit in an integer is an error.
+func builtinLcm(env *object.Environment, args ...object.Object) object.Object {
+	a, b, err := twoIntegers("lcm", args)
+	if err != nil {
+		return err
+	}
+
+	if a == 0 || b == 0 {
+		return &object.Integer{Value: 0}
+	}
+
+	absA, absB := absUint(a), absUint(b)
+	hi, lcm := bits.Mul64(absA/gcd(absA, absB), absB)
+	if hi != 0 || lcm > math.MaxInt64 {
+		return newError("integer overflow in \"lcm\"")
+	}
+
+	return &object.Integer{Value: int64(lcm)}
+}
+
+// gcd implements Euclid's algorithm.
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// absUint returns the absolute value of n, as an unsigned integer so that
+// the absolute value of the smallest int64 is representable.
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// twoIntegers validates that the builtin called name was passed exactly two
+// integers and returns their values.
+func twoIntegers(name string, args []object.Object) (int64, int64, *object.Error) {
+	if len(args) != 2 {
+		return 0, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	first, ok := args[0].(*object.Integer)
+	if !ok {
+		return 0, 0, newError("first argument to %q must be INTEGER, got %s",
+			name, args[0].Type())
+	}
+
+	second, ok := args[1].(*object.Integer)
+	if !ok {
+		return 0, 0, newError("second argument to %q must be INTEGER, got %s",
+			name, args[1].Type())
+	}
+
+	return first.Value, second.Value, nil
+}
+
+// charClassBuiltin returns a builtin called name reporting whether every rune
+// of a non-empty string satisfies inClass.
+func charClassBuiltin(name string, inClass func(rune) bool) object.BuiltinFunction {
+	return func(env *object.Environment, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+
+		str, ok := args[0].(*object.String)
+		if !ok {
+			return newError("argument to %q must be STRING, got %s",
+				name, args[0].Type())
+		}
+
+		if str.Value == "" {
+			return FALSE
+		}
+
+		for _, r := range str.Value {
+			if !inClass(r) {
+				return FALSE
+			}
+		}
+
+		return TRUE
+	}
+}
+
+// isDigitRune reports whether r is an ASCII digit, like the lexer's isDigit.
+func isDigitRune(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+// isAlphaRune reports whether r is an ASCII letter or '_', like the lexer's
+// isLetter, so is_alpha accepts what can start an identifier.
+func isAlphaRune(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_'
+}
+
+// isSpaceRune reports whether r is ASCII whitespace, like the lexer's
+// isWhitespace.
+func isSpaceRune(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	default:
+		return false
+	}
+}
+
+// twoStrings validates that the builtin called name was passed exactly two
+// strings and returns their values.
+func twoStrings(name string, args []object.Object) (string, string, *object.Error) {
+	if len(args) != 2 {
+		return "", "", newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	first, ok := args[0].(*object.String)
+	if !ok {
+		return "", "", newError("first argument to %q must be STRING, got %s",
+			name, args[0].Type())
+	}
+
+	second, ok := args[1].(*object.String)
+	if !ok {
+		return "", "", newError("second argument to %q must be STRING, got %s",
+			name, args[1].Type())
+	}
+
+	return first.Value, second.Value, nil
+}
+
+// arrayAndCount validates the (array, count) arguments of the builtin called
+// name, returning the count clamped to the length of the array.
+func arrayAndCount(
+	name string,
+	args []object.Object,
+) (*object.Array, int, *object.Error) {
+	if len(args) != 2 {
+		return nil, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, 0, newError("first argument to %q must be ARRAY, got %s",
+			name, args[0].Type())
+	}
+
+	count, ok := args[1].(*object.Integer)
+	if !ok {
+		return nil, 0, newError("second argument to %q must be INTEGER, got %s",
+			name, args[1].Type())
+	}
+	if count.Value < 0 {
+		return nil, 0, newError("second argument to %q must not be negative, got %d",
+			name, count.Value)
+	}
+
+	n := len(array.Elements)
+	if count.Value < int64(n) {
+		n = int(count.Value)
+	}
+
+	return array, n, nil
+}
+
+// objectsEqual reports whether a and b are equal by value. Arrays and hashes
+// are compared element by element, functions only equal themselves.
+func objectsEqual(a, b object.Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *object.Integer:
+		return a.Value == b.(*object.Integer).Value
+	case *object.Float:
+		return a.Value == b.(*object.Float).Value
+	case *object.Boolean:
+		return a.Value == b.(*object.Boolean).Value
+	case *object.String:
+		return a.Value == b.(*object.String).Value
+	case *object.Null:
+		return true
+	case *object.Array:
+		other := b.(*object.Array)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i, el := range a.Elements {
+			if !objectsEqual(el, other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		other := b.(*object.Hash)
+		if len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !objectsEqual(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
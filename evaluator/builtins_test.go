@@ -0,0 +1,1068 @@
+package evaluator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dominicgaliano/interpreter-demo/lexer"
+	"github.com/dominicgaliano/interpreter-demo/object"
+	"github.com/dominicgaliano/interpreter-demo/parser"
+)
+
+func TestBuiltinLen(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len("héllo")`, 6},
+		{"len([])", 0},
+		{"len([1, 2, 3])", 3},
+		{`len({"a": 1, "b": 2})`, 2},
+		{"let len = fn(x) { 42 }; len([1])", 42},
+		{"len(1)", errorMessage(`argument to "len" not supported, got INTEGER`)},
+		{"len(fn(x) { x })", errorMessage(`argument to "len" not supported, got FUNCTION`)},
+		{`len("one", "two")`, errorMessage("wrong number of arguments. got=2, want=1")},
+		{"len()", errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinPuts(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`puts(1, "two", true)`, "1\ntwo\ntrue\n"},
+		{"puts([1, 2], 1.5)", "[1, 2]\n1.5\n"},
+		{"puts()", ""},
+		{"puts(1); puts(2)", "1\n2\n"},
+		// functions print to the output of the call, not of their definition
+		{"map([1, 2], puts); puts()", "1\n2\n"},
+		{"let f = fn(x) { puts(x) }; times(2, f); puts()", "0\n1\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		env := object.NewEnclosedEnviroment(Prelude())
+		env.SetOutput(&out)
+
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		testNullObject(t, Eval(program, env))
+		if out.String() != tt.expected {
+			t.Errorf("wrong output for %q. got=%q, want=%q",
+				tt.input, out.String(), tt.expected)
+		}
+	}
+}
+
+func TestBuiltinPutsSeparateOutputs(t *testing.T) {
+	// a function defined in one environment and called from another prints
+	// to the output of the caller
+	var first, second bytes.Buffer
+	definer := object.NewEnvironment()
+	definer.SetOutput(&first)
+	Eval(parser.New(lexer.New("let say = fn(x) { puts(x) };")).ParseProgram(), definer)
+
+	caller := object.NewEnclosedEnviroment(definer)
+	caller.SetOutput(&second)
+	Eval(parser.New(lexer.New("say(1)")).ParseProgram(), caller)
+	Eval(parser.New(lexer.New("say(2)")).ParseProgram(), definer)
+
+	if first.String() != "2\n" || second.String() != "1\n" {
+		t.Errorf("wrong outputs. got=%q and %q, want=%q and %q",
+			first.String(), second.String(), "2\n", "1\n")
+	}
+}
+
+func TestBuiltinKeyString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"key_string(1)", "INTEGER:1"},
+		{`key_string("1")`, `STRING:"1"`},
+		{"key_string(true)", "BOOLEAN:true"},
+		{`key_string("true")`, `STRING:"true"`},
+		{`key_string([1, "1", [true]])`, `ARRAY:[INTEGER:1, STRING:"1", ARRAY:[BOOLEAN:true]]`},
+		{`key_string(1) == key_string("1")`, false},
+		{`key_string([1, 2]) == key_string([1, 1 + 1])`, true},
+		{"key_string(fn(x) { x })", errorMessage("unusable as hash key: FUNCTION")},
+		{"key_string([1, {}])", errorMessage("unusable as hash key: ARRAY")},
+		{"key_string(1.5)", errorMessage("unusable as hash key: FLOAT")},
+		{"key_string()", errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinAssocIn(t *testing.T) {
+	data := `let data = {"users": [{"name": "Ann"}, {"name": "Bob"}], "count": 2};`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{data + `assoc_in(data, ["users", 1, "name"], "Cy")["users"][1]["name"]`, "Cy"},
+		{data + `assoc_in(data, ["users", -1, "name"], "Cy")["users"][1]["name"]`, "Cy"},
+		{data + `assoc_in(data, ["users", 1, "name"], "Cy")["users"][0]["name"]`, "Ann"},
+		{data + `assoc_in(data, ["count"], 3)["count"]`, 3},
+		{data + `assoc_in(data, ["users", 0, "age"], 30)["users"][0]["age"]`, 30},
+		{data + `assoc_in(data, ["new", "nested"], 1)["new"]["nested"]`, 1},
+		{data + `assoc_in(data, [], 5)`, 5},
+		{`assoc_in([[1, 2], [3, 4]], [1, 0], 9)[1]`, []int64{9, 4}},
+		{data + `assoc_in(data, ["users", 2, "name"], "Cy")`,
+			errorMessage(`index out of range in path to "assoc_in": 2`)},
+		{data + `assoc_in(data, ["users", "0"], 1)`,
+			errorMessage(`array index in path to "assoc_in" must be INTEGER, got STRING`)},
+		{data + `assoc_in(data, ["users", 0, "name", 0], 1)`,
+			errorMessage(`path to "assoc_in" cannot index into STRING`)},
+		{data + `assoc_in(data, [fn(x) { x }], 1)`, errorMessage("unusable as hash key: FUNCTION")},
+		{`assoc_in({}, "a", 1)`, errorMessage(`second argument to "assoc_in" must be ARRAY, got STRING`)},
+		{`assoc_in({}, ["a"])`, errorMessage("wrong number of arguments. got=2, want=3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinAssocInLeavesOriginalUnchanged(t *testing.T) {
+	input := `let data = {"users": [{"name": "Ann"}, {"name": "Bob"}]};
+let updated = assoc_in(data, ["users", 1, "name"], "Cy");
+[data, updated]`
+
+	result, ok := testEval(input).(*object.Array)
+	if !ok {
+		t.Fatalf("result is not Array")
+	}
+
+	original, updated := result.Elements[0].Inspect(), result.Elements[1].Inspect()
+	if original != `{users: [{name: Ann}, {name: Bob}]}` {
+		t.Errorf("original changed. got=%s", original)
+	}
+	if updated != `{users: [{name: Ann}, {name: Cy}]}` {
+		t.Errorf("wrong update. got=%s", updated)
+	}
+
+	// containers off the path are shared rather than copied
+	usersKey := (&object.String{Value: "users"}).HashKey()
+	originalUsers := result.Elements[0].(*object.Hash).Pairs[usersKey].Value.(*object.Array)
+	updatedUsers := result.Elements[1].(*object.Hash).Pairs[usersKey].Value.(*object.Array)
+	if originalUsers == updatedUsers {
+		t.Errorf("array on the path was not copied")
+	}
+	if originalUsers.Elements[0] != updatedUsers.Elements[0] {
+		t.Errorf("element off the path was copied")
+	}
+}
+
+func TestBuiltinGetIn(t *testing.T) {
+	data := `let data = {"users": [{"name": "Ann"}, {"name": "Bob"}], 1: true};`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{data + `get_in(data, ["users", 1, "name"], "none")`, "Bob"},
+		{data + `get_in(data, ["users", -2, "name"], "none")`, "Ann"},
+		{data + `get_in(data, [1])`, true},
+		{data + `get_in(data, [], "none") == data`, true},
+		{data + `get_in(data, ["users", 0, "age"], 0)`, 0},
+		{data + `get_in(data, ["missing", 0], "none")`, "none"},
+		{data + `get_in(data, ["users", 2, "name"], "none")`, "none"},
+		{data + `get_in(data, ["users", -3], "none")`, "none"},
+		{data + `get_in(data, ["users", 2, "name"])`, nil},
+		{data + `get_in(data, ["users", "0"], "none")`, "none"},
+		{data + `get_in(data, ["users", 0, "name", 0], "none")`, "none"},
+		{data + `get_in(data, [fn(x) { x }], "none")`, "none"},
+		{`get_in({}, "a")`, errorMessage(`second argument to "get_in" must be ARRAY, got STRING`)},
+		{`get_in({})`, errorMessage("wrong number of arguments. got=1, want=2 or 3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinFirstLastRest(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"first([1, 2, 3])", 1},
+		{"first([])", nil},
+		{"last([1, 2, 3])", 3},
+		{"last([])", nil},
+		{"rest([1, 2, 3])", []int64{2, 3}},
+		{"rest(rest([1, 2, 3]))", []int64{3}},
+		{"rest([1])", []int64{}},
+		{"rest([])", nil},
+		{"let a = [1, 2, 3]; rest(a); a", []int64{1, 2, 3}},
+		{"first(1)", errorMessage(`argument to "first" must be ARRAY, got INTEGER`)},
+		{`last("abc")`, errorMessage(`argument to "last" must be ARRAY, got STRING`)},
+		{"rest({})", errorMessage(`argument to "rest" must be ARRAY, got HASH`)},
+		{"first([1], [2])", errorMessage("wrong number of arguments. got=2, want=1")},
+		{"last()", errorMessage("wrong number of arguments. got=0, want=1")},
+		{"rest([1], [2])", errorMessage("wrong number of arguments. got=2, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinPush(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"push([], 1)", []int64{1}},
+		{"push([1, 2], 3)", []int64{1, 2, 3}},
+		{"let a = [1, 2]; push(a, 3); a", []int64{1, 2}},
+		{"let a = [1, 2]; let b = push(a, 3); let c = push(a, 4); b", []int64{1, 2, 3}},
+		{"let a = push([1], 2); let b = push(a, 3); let c = push(a, 4); b", []int64{1, 2, 3}},
+		{"push(1, 1)", errorMessage(`first argument to "push" must be ARRAY, got INTEGER`)},
+		{"push([1])", errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinReverse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"reverse([1, 2, 3])", []int64{3, 2, 1}},
+		{"reverse([])", []int64{}},
+		{"let a = [1, 2, 3]; let b = reverse(a); a", []int64{1, 2, 3}},
+		{`reverse("abc")`, "cba"},
+		{`reverse("")`, ""},
+		{`reverse("héllo, 世界")`, "界世 ,olléh"},
+		{"reverse(1)", errorMessage(`argument to "reverse" must be ARRAY or STRING, got INTEGER`)},
+		{"reverse([1], [2])", errorMessage("wrong number of arguments. got=2, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinPartial(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(a, b, c) { a + b + c }; partial(add, 1, 2)(3)", 6},
+		{"let add = fn(a, b, c) { a + b + c }; partial(add, 1)(2, 3)", 6},
+		{"let add = fn(a, b, c) { a + b + c }; partial(partial(add, 1), 2)(3)", 6},
+		{"let add = fn(a, b, c) { a + b + c }; partial(add)(1, 2, 3)", 6},
+		{"let sub = fn(a, b) { a - b }; let f = partial(sub, 10); f(3) + f(4)", 13},
+		{"partial(reverse)([1, 2])", []int64{2, 1}},
+		{
+			"let add = fn(a, b, c) { a + b + c }; partial(add, 1, 2)(3, 4)",
+			errorMessage("too many arguments to partial function. got=4, want=3"),
+		},
+		{
+			"let add = fn(a, b, c) { a + b + c }; partial(add, 1)(2)",
+			errorMessage("function call missing arguments, got=2, expected=3"),
+		},
+		{"partial(1, 2)", errorMessage(`argument to "partial" must be FUNCTION, got INTEGER`)},
+		{"partial()", errorMessage("wrong number of arguments. got=0, want at least 1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinCompose(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			"let inc = fn(x) { x + 1 }; let double = fn(x) { x * 2 }; compose(inc, double)(5)",
+			11,
+		},
+		{
+			"let inc = fn(x) { x + 1 }; let double = fn(x) { x * 2 }; compose(double, inc)(5)",
+			12,
+		},
+		{
+			`let inc = fn(x) { x + 1 };
+let double = fn(x) { x * 2 };
+let negate = fn(x) { -x };
+compose(negate, inc, double)(5)`,
+			-11,
+		},
+		{"let add = fn(a, b) { a + b }; compose(fn(x) { x * 10 }, add)(1, 2)", 30},
+		{"compose(reverse)([1, 2, 3])", []int64{3, 2, 1}},
+		{
+			"let bad = fn(x) { x + true }; let inc = fn(x) { x + 1 }; compose(inc, bad)(1)",
+			errorMessage("type mismatch: INTEGER + BOOLEAN"),
+		},
+		{
+			"let bad = fn(x) { x + true }; let inc = fn(x) { x + 1 }; compose(bad, inc, inc)(1)",
+			errorMessage("type mismatch: INTEGER + BOOLEAN"),
+		},
+		{"compose(fn(x) { x }, 1)", errorMessage(`argument to "compose" must be FUNCTION, got INTEGER`)},
+		{"compose()", errorMessage("wrong number of arguments. got=0, want at least 1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinTimes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"times(3, fn(i) { i * i })", []int64{0, 1, 4}},
+		{"times(0, fn(i) { i })", []int64{}},
+		{"let base = 10; times(2, fn(i) { base + i })", []int64{10, 11}},
+		{"times(3, fn(i) { if (i == 1) { i + true } else { i } })", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"times(-1, fn(i) { i })", errorMessage(`first argument to "times" must not be negative, got -1`)},
+		{"times(9223372036854775807, fn(i) { i })", errorMessage(`first argument to "times" is too large, maximum is 16777216`)},
+		{"times(16777217, fn(i) { i })", errorMessage(`first argument to "times" is too large, maximum is 16777216`)},
+		{"times(true, fn(i) { i })", errorMessage(`first argument to "times" must be INTEGER, got BOOLEAN`)},
+		{"times(3, 3)", errorMessage(`second argument to "times" must be FUNCTION, got INTEGER`)},
+		{"times(3)", errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinId(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"id(true) == id(1 < 2)", true},
+		{"id(false) == id(!true)", true},
+		{"id(true) == id(false)", false},
+		{"id(if (false) { 1 }) == id(if (false) { 2 })", true},
+		{"id([1, 2]) == id([1, 2])", false},
+		{"let a = [1, 2]; let b = a; id(a) == id(b)", true},
+		{"let a = [1, 2]; id(a) == id(reverse(reverse(a)))", false},
+		{"let f = fn() { 1 }; let g = f; id(f) == id(g)", true},
+		{"id(5)", -1},
+		{`id("monkey")`, -1},
+		{"id()", errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinCopy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"copy([1, 2, 3])", []int64{1, 2, 3}},
+		{"copy(5)", 5},
+		{`copy("monkey")`, "monkey"},
+		{`copy({"a": [1, 2]})["a"]`, []int64{1, 2}},
+		{"let a = [1, [2]]; id(copy(a)) == id(a)", false},
+		{"let a = [1, [2]]; id(copy(a)[1]) == id(a[1])", false},
+		{"let f = fn(x) { x }; id(copy(f)) == id(f)", true},
+		{"copy(true) == true", true},
+		{"copy()", errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinCopyIsDeep(t *testing.T) {
+	original := testEval(`let a = [1, [2, 3], {"k": [4]}]; a`).(*object.Array)
+	copied := builtinCopy(object.NewEnvironment(), original).(*object.Array)
+
+	// mutate every level of the copy
+	copied.Elements[0] = &object.Integer{Value: 100}
+	copied.Elements[1].(*object.Array).Elements[0] = &object.Integer{Value: 200}
+	for _, pair := range copied.Elements[2].(*object.Hash).Pairs {
+		pair.Value.(*object.Array).Elements[0] = &object.Integer{Value: 300}
+	}
+
+	expected := `[1, [2, 3], {k: [4]}]`
+	if original.Inspect() != expected {
+		t.Errorf("original was modified. got=%s, want=%s",
+			original.Inspect(), expected)
+	}
+}
+
+func TestBuiltinExit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"exit()", 0},
+		{"exit(3)", 3},
+		{"exit(2); 5", 2},
+		{"let f = fn() { exit(4); 1 }; f() + 1", 4},
+		{"if (true) { exit(1); }; 2", 1},
+		{"times(3, fn(i) { if (i == 1) { exit(i) } })", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		exit, ok := evaluated.(*object.Exit)
+		if !ok {
+			t.Errorf("object is not Exit for %q. got=%T (%+v)",
+				tt.input, evaluated, evaluated)
+			continue
+		}
+		if exit.Code != tt.expected {
+			t.Errorf("wrong code for %q. got=%d, want=%d",
+				tt.input, exit.Code, tt.expected)
+		}
+	}
+
+	testErrorObject(t, testEval(`exit("1")`),
+		"argument to \"exit\" must be INTEGER, got STRING")
+	testErrorObject(t, testEval("exit(1, 2)"),
+		"wrong number of arguments. got=2, want=0 or 1")
+}
+
+func TestBuiltinHasKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`has_key({"a": 1}, "a")`, true},
+		{`has_key({"a": 1}, "b")`, false},
+		{`has_key({}, 1)`, false},
+		{`has_key({1: if (false) { 1 }}, 1)`, true},
+		{`has_key({true: 1, [1, 2]: 2}, [1, 2])`, true},
+		{`has_key({"1": 1}, 1)`, false},
+		{`has_key([1], 0)`, errorMessage("first argument to \"has_key\" must be HASH, got ARRAY")},
+		{`has_key({}, fn(x) { x })`, errorMessage("unusable as hash key: FUNCTION")},
+		{`has_key({})`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinHasValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`has_value({"a": 1}, 1)`, true},
+		{`has_value({"a": 1}, "a")`, false},
+		{`has_value({"a": 1, "b": "1"}, "1")`, true},
+		{`has_value({"a": 1}, if (false) { 1 })`, false},
+		{`has_value({"a": [1, [2]]}, [1, [2]])`, true},
+		{`has_value({"a": [1, [2]]}, [1, [3]])`, false},
+		{`has_value({"a": {"b": 1}}, {"b": 1})`, true},
+		{`has_value({"a": fn(x) { x }}, fn(x) { x })`, false},
+		{`let f = fn(x) { x }; has_value({"a": f}, f)`, true},
+		{`has_value("a", "a")`, errorMessage("first argument to \"has_value\" must be HASH, got STRING")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinSet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`set({}, "a", 1)["a"]`, 1},
+		{`set({"a": 1}, "b", 2)["a"]`, 1},
+		{`set({"a": 1}, "a", 2)["a"]`, 2},
+		{`let h = {"a": 1}; set(h, "a", 2); h["a"]`, 1},
+		{`let h = {"a": 1}; set(h, "b", 2); h["b"]`, nil},
+		{`set(set({}, [1], "x"), [1], "y")[[1]]`, "y"},
+		{`set({}, fn(x) { x }, 1)`, errorMessage("unusable as hash key: FUNCTION")},
+		{`set([], 0, 1)`, errorMessage("first argument to \"set\" must be HASH, got ARRAY")},
+		{`set({}, 1)`, errorMessage("wrong number of arguments. got=2, want=3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinGet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`get({"a": 1}, "a")`, 1},
+		{`get({"a": 1}, "b")`, nil},
+		{`get({"a": 1}, "a", 5)`, 1},
+		{`get({"a": 1}, "b", 5)`, 5},
+		{`get({}, [1, 2], "none")`, "none"},
+		{`get({}, [fn(x) { x }], 1)`, errorMessage("unusable as hash key: ARRAY")},
+		{`get("a", "a")`, errorMessage("first argument to \"get\" must be HASH, got STRING")},
+		{`get({})`, errorMessage("wrong number of arguments. got=1, want=2 or 3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinFlatten(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"flatten([[1, 2], [3, [4]]])", []int64{1, 2, 3, 4}},
+		{"flatten([[[[1]]], [], 2])", []int64{1, 2}},
+		{"flatten([1, 2, 3])", []int64{1, 2, 3}},
+		{"flatten([])", []int64{}},
+		{"flatten([[1, 2], [3, [4]]], 1)[3][0]", 4},
+		{"flatten([[1, 2], [3, [4]]], 1)[2]", 3},
+		{"flatten([[1], [[2]]], 2)", []int64{1, 2}},
+		{"flatten([[1]], 0)[0]", []int64{1}},
+		{`flatten([["a"], "b"])[1]`, "b"},
+		{"let a = [[1], 2]; flatten(a); a[0]", []int64{1}},
+		{"flatten(1)", errorMessage("first argument to \"flatten\" must be ARRAY, got INTEGER")},
+		{"flatten([], -1)", errorMessage("second argument to \"flatten\" must not be negative, got -1")},
+		{`flatten([], "1")`, errorMessage("second argument to \"flatten\" must be INTEGER, got STRING")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinUnique(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"unique([1, 2, 2, 3, 1])", []int64{1, 2, 3}},
+		{"unique([3, 1, 2])", []int64{3, 1, 2}},
+		{"unique([])", []int64{}},
+		{"let a = [1, 1]; unique(a); a", []int64{1, 1}},
+		{`unique(["a", "b", "a"])[1]`, "b"},
+		{`unique([1, "1", true])[1]`, "1"},
+		{"unique([[1, 2], [1, 2], [2]])[1]", []int64{2}},
+		{`unique([{"a": 1}, {"a": 1}, {"a": 2}])[1]["a"]`, 2},
+		{`unique([{"a": 1}, {"a": 1}])[1]`, nil},
+		{"let f = fn(x) { x }; unique([f, f, fn(x) { x }])[2]", nil},
+		{"unique(1)", errorMessage("argument to \"unique\" must be ARRAY, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinTakeAndDrop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"take([1, 2, 3], 2)", []int64{1, 2}},
+		{"take([1, 2, 3], 0)", []int64{}},
+		{"take([1, 2, 3], 5)", []int64{1, 2, 3}},
+		{"take([], 1)", []int64{}},
+		{"drop([1, 2, 3], 2)", []int64{3}},
+		{"drop([1, 2, 3], 0)", []int64{1, 2, 3}},
+		{"drop([1, 2, 3], 5)", []int64{}},
+		{"let a = [1, 2, 3]; take(a, 1); drop(a, 1); a", []int64{1, 2, 3}},
+		{"take([1], -1)", errorMessage("second argument to \"take\" must not be negative, got -1")},
+		{"drop([1], -2)", errorMessage("second argument to \"drop\" must not be negative, got -2")},
+		{`take("ab", 1)`, errorMessage("first argument to \"take\" must be ARRAY, got STRING")},
+		{`drop([1], "1")`, errorMessage("second argument to \"drop\" must be INTEGER, got STRING")},
+		{"take([1])", errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinGroupBy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let g = group_by([1, 2, 3, 4, 5], fn(x) { x - x / 2 * 2 }); g[0]", []int64{2, 4}},
+		{"let g = group_by([1, 2, 3, 4, 5], fn(x) { x - x / 2 * 2 }); g[1]", []int64{1, 3, 5}},
+		{"let g = group_by([1, 2, 3], fn(x) { x > 1 }); g[true]", []int64{2, 3}},
+		{"group_by([], fn(x) { x })[1]", nil},
+		{"group_by([1], fn(x) { fn() { x } })",
+			errorMessage("unusable as hash key: FUNCTION")},
+		{"group_by([1], fn(x) { x + true })",
+			errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"group_by(1, fn(x) { x })",
+			errorMessage("first argument to \"group_by\" must be ARRAY, got INTEGER")},
+		{"group_by([1], 1)",
+			errorMessage("second argument to \"group_by\" must be FUNCTION, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinCount(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let c = count([1, 2, 2, 3, 2]); c[2]", 3},
+		{"let c = count([1, 2, 2, 3, 2]); c[1]", 1},
+		{"let c = count([1, 2, 2, 3, 2]); c[4]", nil},
+		{`let c = count(["a", "b", "a"]); c["a"]`, 2},
+		{"count([[1], [1]])[[1]]", 2},
+		{"count([1, 2, 3, 4, 5, 6], fn(x) { x / 2 * 2 == x })", 3},
+		{"count([1, 2, 3], fn(x) { x > 5 })", 0},
+		{"count([], fn(x) { true })", 0},
+		{"count([fn(x) { x }])", errorMessage("unusable as hash key: FUNCTION")},
+		{"count([1], fn(x) { -true })", errorMessage("unknown operator: -BOOLEAN")},
+		{"count([1], 1)", errorMessage("second argument to \"count\" must be FUNCTION, got INTEGER")},
+		{"count(1)", errorMessage("first argument to \"count\" must be ARRAY, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinChunk(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"chunk([1, 2, 3, 4, 5], 2)[0]", []int64{1, 2}},
+		{"chunk([1, 2, 3, 4, 5], 2)[1]", []int64{3, 4}},
+		{"chunk([1, 2, 3, 4, 5], 2)[2]", []int64{5}},
+		{"chunk([1, 2, 3, 4, 5], 2)[3]", nil},
+		{"chunk([1, 2, 3, 4], 2)[1]", []int64{3, 4}},
+		{"chunk([1, 2, 3, 4], 2)[2]", nil},
+		{"chunk([1, 2], 5)[0]", []int64{1, 2}},
+		{"chunk([], 3)", []int64{}},
+		{"let a = [1, 2, 3]; chunk(a, 2); a", []int64{1, 2, 3}},
+		{"chunk([1], 0)", errorMessage("second argument to \"chunk\" must be positive, got 0")},
+		{"chunk([1], -1)", errorMessage("second argument to \"chunk\" must be positive, got -1")},
+		{"chunk(1, 1)", errorMessage("first argument to \"chunk\" must be ARRAY, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinEveryAndSome(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"every([2, 4, 6], fn(x) { x / 2 * 2 == x })", true},
+		{"every([2, 3, 6], fn(x) { x / 2 * 2 == x })", false},
+		{"every([1, 3, 5], fn(x) { x / 2 * 2 == x })", false},
+		{"every([], fn(x) { false })", true},
+		{"some([2, 4, 6], fn(x) { x / 2 * 2 == x })", true},
+		{"some([1, 3, 6], fn(x) { x / 2 * 2 == x })", true},
+		{"some([1, 3, 5], fn(x) { x / 2 * 2 == x })", false},
+		{"some([], fn(x) { true })", false},
+		// both stop at the first element that decides the result
+		{"every([1, true], fn(x) { x > 1 })", false},
+		{"some([1, true], fn(x) { x == 1 })", true},
+		{"every([1, true], fn(x) { x > 0 })",
+			errorMessage("type mismatch: BOOLEAN > INTEGER")},
+		{"some(1, fn(x) { x })",
+			errorMessage("first argument to \"some\" must be ARRAY, got INTEGER")},
+		{"every([1], 1)",
+			errorMessage("second argument to \"every\" must be FUNCTION, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinFind(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"find([1, 2, 3, 4], fn(x) { x > 2 })", 3},
+		{"find([1, 2, 3], fn(x) { x > 5 })", nil},
+		{"find([], fn(x) { true })", nil},
+		{`find(["a", "b"], fn(x) { true })`, "a"},
+		{"find_index([1, 2, 3, 4], fn(x) { x > 2 })", 2},
+		{"find_index([1, 2, 3], fn(x) { x > 5 })", -1},
+		{"find_index([], fn(x) { true })", -1},
+		// both stop at the first match
+		{"find([1, true], fn(x) { x == 1 })", 1},
+		{"find_index([1, true], fn(x) { x == 1 })", 0},
+		{"find([true], fn(x) { -x })", errorMessage("unknown operator: -BOOLEAN")},
+		{"find_index({}, fn(x) { x })",
+			errorMessage("first argument to \"find_index\" must be ARRAY, got HASH")},
+		{"find([1], 1)",
+			errorMessage("second argument to \"find\" must be FUNCTION, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinPad(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`pad_left("7", 3)`, "  7"},
+		{`pad_right("7", 3)`, "7  "},
+		{`pad_left("abc", 3)`, "abc"},
+		{`pad_right("abcd", 2)`, "abcd"},
+		{`pad_left("", 0)`, ""},
+		{`pad_left("7", 3, "0")`, "007"},
+		{`pad_right("ab", 4, ".")`, "ab.."},
+		{`pad_left("é", 3, "·")`, "··é"},
+		{`pad_right("日本", 3)`, "日本 "},
+		{`pad_left("a", -1)`,
+			errorMessage("second argument to \"pad_left\" must not be negative, got -1")},
+		{`pad_right("a", 3, "ab")`,
+			errorMessage("third argument to \"pad_right\" must be a single character, got ab")},
+		{`pad_left("a", 3, 0)`,
+			errorMessage("third argument to \"pad_left\" must be a single character, got 0")},
+		{`pad_left(1, 3)`,
+			errorMessage("first argument to \"pad_left\" must be STRING, got INTEGER")},
+		{`pad_right("a")`, errorMessage("wrong number of arguments. got=1, want=2 or 3")},
+		{`pad_left("a", 9223372036854775807)`,
+			errorMessage("result of \"pad_left\" is too long, maximum length is 16777216")},
+		{`pad_right("a", 100000000000)`,
+			errorMessage("result of \"pad_right\" is too long, maximum length is 16777216")},
+		// the limit is in bytes, a two-byte fill reaches it at half the width
+		{`pad_left("", 8388609, "é")`,
+			errorMessage("result of \"pad_left\" is too long, maximum length is 16777216")},
+		{`len(pad_right("", 16777216))`, 16777216},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinRepeat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 1)`, "ab"},
+		{`repeat("ab", 0)`, ""},
+		{`repeat("ab", -2)`, ""},
+		{`repeat("", 9223372036854775807)`, ""},
+		{`repeat("x", 16777217)`,
+			errorMessage("result of \"repeat\" is too long, maximum length is 16777216")},
+		{`repeat("ab", 9223372036854775807)`,
+			errorMessage("result of \"repeat\" is too long, maximum length is 16777216")},
+		{`repeat(1, 2)`, errorMessage("first argument to \"repeat\" must be STRING, got INTEGER")},
+		{`repeat("a", "2")`, errorMessage("second argument to \"repeat\" must be INTEGER, got STRING")},
+		{`repeat("a")`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	// exactly the maximum length is still allowed
+	str, ok := testEval(`repeat("ab", 8388608)`).(*object.String)
+	if !ok || len(str.Value) != maxRepeatLength {
+		t.Errorf("repeat up to the maximum length failed. got=%T", str)
+	}
+}
+
+func TestBuiltinStartsAndEndsWith(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`starts_with("monkey", "mon")`, true},
+		{`starts_with("monkey", "key")`, false},
+		{`starts_with("monkey", "")`, true},
+		{`starts_with("", "a")`, false},
+		{`ends_with("monkey", "key")`, true},
+		{`ends_with("monkey", "mon")`, false},
+		{`ends_with("monkey", "")`, true},
+		{`ends_with("mon", "monkey")`, false},
+		{`starts_with(1, "1")`,
+			errorMessage("first argument to \"starts_with\" must be STRING, got INTEGER")},
+		{`ends_with("1", 1)`,
+			errorMessage("second argument to \"ends_with\" must be STRING, got INTEGER")},
+		{`ends_with("1")`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinCharClasses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`is_digit("0123456789")`, true},
+		{`is_digit("12a")`, false},
+		{`is_digit("")`, false},
+		{`is_digit("١")`, false},
+		{`is_alpha("abcXYZ")`, true},
+		{`is_alpha("ab1")`, false},
+		{`is_alpha("a_b")`, true},
+		{`is_alpha("_")`, true},
+		{`is_alpha("é")`, false},
+		{`is_alpha("")`, false},
+		{`is_space(" ")`, true},
+		{`is_space(" a ")`, false},
+		{`is_space("")`, false},
+		{`is_digit(1)`, errorMessage("argument to \"is_digit\" must be STRING, got INTEGER")},
+		{`is_space("a", "b")`, errorMessage("wrong number of arguments. got=2, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinCharsAndFromChars(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`chars("abc")[0]`, "a"},
+		{`chars("abc")[2]`, "c"},
+		{`chars("abc")[3]`, nil},
+		{`chars("")`, []int64{}},
+		{`chars("日本語")[1]`, "本"},
+		{`chars("héllo")[1]`, "é"},
+		{`from_chars(chars("monkey"))`, "monkey"},
+		{`from_chars(chars("日本語"))`, "日本語"},
+		{`from_chars(reverse(chars("héllo")))`, "olléh"},
+		{`from_chars([])`, ""},
+		{`chars(1)`, errorMessage("argument to \"chars\" must be STRING, got INTEGER")},
+		{`from_chars("abc")`, errorMessage("argument to \"from_chars\" must be ARRAY, got STRING")},
+		{`from_chars(["a", "bc"])`,
+			errorMessage("element 1 of \"from_chars\" argument must be a single character, got bc")},
+		{`from_chars([1])`,
+			errorMessage("element 0 of \"from_chars\" argument must be a single character, got 1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinToInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`to_int("42")`, 42},
+		{`to_int("-17")`, -17},
+		{`to_int("ff", 16)`, 255},
+		{`to_int("FF", 16)`, 255},
+		{`to_int("101", 2)`, 5},
+		{`to_int("z", 36)`, 35},
+		{`to_int("monkey")`, nil},
+		{`to_int("")`, nil},
+		{`to_int("1.5")`, nil},
+		{`to_int(" 1")`, nil},
+		{`to_int("ff")`, nil},
+		{`to_int("2", 2)`, nil},
+		{`to_int("99999999999999999999")`, nil},
+		{`to_int(1)`, errorMessage("first argument to \"to_int\" must be STRING, got INTEGER")},
+		{`to_int("1", 1)`,
+			errorMessage("second argument to \"to_int\" must be between 2 and 36, got 1")},
+		{`to_int("1", "2")`,
+			errorMessage("second argument to \"to_int\" must be INTEGER, got STRING")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinClamp(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"clamp(-5, 0, 10)", 0},
+		{"clamp(5, 0, 10)", 5},
+		{"clamp(15, 0, 10)", 10},
+		{"clamp(3, 3, 3)", 3},
+		{"clamp(1.5, 0, 1)", 1.0},
+		{"clamp(0.5, 0, 1)", 0.5},
+		{"clamp(5, 0.5, 2.5)", 2.5},
+		{"clamp(-1, 0.5, 2)", 0.5},
+		{"clamp(1, 0, 2.0)", 1.0},
+		{"clamp(9223372036854775807, 0, 9223372036854775806)", 9223372036854775806},
+		{"clamp(5, 10, 0)",
+			errorMessage("lower bound of \"clamp\" is greater than upper bound, 10 > 0")},
+		{"clamp(5, 1.5, 0.5)",
+			errorMessage("lower bound of \"clamp\" is greater than upper bound, 1.5 > 0.5")},
+		{`clamp("5", 0, 10)`,
+			errorMessage("arguments to \"clamp\" must be INTEGER or FLOAT, got STRING")},
+		{"clamp(5, 0)", errorMessage("wrong number of arguments. got=2, want=3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinGcdAndLcm(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"gcd(12, 18)", 6},
+		{"gcd(7, 13)", 1},
+		{"gcd(-12, 18)", 6},
+		{"gcd(0, 5)", 5},
+		{"gcd(0, 0)", 0},
+		{"lcm(4, 6)", 12},
+		{"lcm(7, 13)", 91},
+		{"lcm(-4, 6)", 12},
+		{"lcm(0, 5)", 0},
+		{"lcm(0, 0)", 0},
+		{"lcm(4294967296, 4294967296)", 4294967296},
+		{"lcm(4294967296, 4294967297)", errorMessage("integer overflow in \"lcm\"")},
+		{"gcd(-9223372036854775807 - 1, 0)", errorMessage("integer overflow in \"gcd\"")},
+		{"gcd(-9223372036854775807 - 1, 6)", 2},
+		{"gcd(1.5, 2)", errorMessage("first argument to \"gcd\" must be INTEGER, got FLOAT")},
+		{`lcm(2, "3")`, errorMessage("second argument to \"lcm\" must be INTEGER, got STRING")},
+		{"gcd(1)", errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinArity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"arity(fn(x, y) { x + y })", 2},
+		{"arity(fn() { 1 })", 0},
+		{"let f = fn(a, b, c) { a }; arity(f)", 3},
+		{"arity(partial)", unknownArity},
+		{"arity(partial(fn(x, y) { x + y }, 1))", unknownArity},
+		{"arity(arity)", unknownArity},
+		{"arity(1)", errorMessage("argument to \"arity\" must be FUNCTION, got INTEGER")},
+		{"arity()", errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinCurry(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = curry(fn(a, b, c) { a + b * c }); add(1)(2)(3)", 7},
+		{"let add = curry(fn(a, b, c) { a + b * c }); add(1, 2)(3)", 7},
+		{"let add = curry(fn(a, b, c) { a + b * c }); add(1)(2, 3)", 7},
+		{"let add = curry(fn(a, b, c) { a + b * c }); add(1, 2, 3)", 7},
+		{"let add = curry(fn(a, b, c) { a + b * c }); add()(1)()(2)(3)", 7},
+		// partially applied functions are independent of each other
+		{"let add = curry(fn(a, b) { a - b }); let one = add(1); one(5) + one(2)", -5},
+		{"curry(fn() { 42 })()", 42},
+		{"curry(fn(a, b) { a })(1, 2, 3)",
+			errorMessage("too many arguments to curried function. got=3, want=2")},
+		{"curry(fn(a) { a })(1)(2)", errorMessage("not a function: INTEGER")},
+		{"curry(partial)", errorMessage("cannot curry a builtin function, its arity is unknown")},
+		{"curry(1)", errorMessage("argument to \"curry\" must be FUNCTION, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinTap(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"tap(5, fn(x) { x * 100 })", 5},
+		{"5 |> tap(fn(x) { 0 }) |> fn(x) { x + 1 }", 6},
+		{"[1, 2] |> tap(reverse)", []int64{1, 2}},
+		{"tap(5, fn(x) { x + true })", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"tap(5, 5)", errorMessage("second argument to \"tap\" must be FUNCTION, got INTEGER")},
+		{"tap(5)", errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinTapObservesValue(t *testing.T) {
+	var observed []object.Object
+	record := &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		observed = append(observed, args...)
+		return NULL
+	}}
+
+	env := object.NewEnvironment()
+	env.Set("record", record)
+
+	program := parser.New(lexer.New("3 |> tap(record) |> fn(x) { x * 2 }")).ParseProgram()
+	testIntegerObject(t, Eval(program, env), 6)
+
+	if len(observed) != 1 {
+		t.Fatalf("tap did not call fn exactly once. got=%d calls", len(observed))
+	}
+	testIntegerObject(t, observed[0], 3)
+}
+
+func TestBuiltinType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"type(1)", "INTEGER"},
+		{"type(1.5)", "FLOAT"},
+		{`type("a")`, "STRING"},
+		{"type(true)", "BOOLEAN"},
+		{"type([])", "ARRAY"},
+		{"type({})", "HASH"},
+		{"type(fn() {})", "FUNCTION"},
+		{"type(type)", "BUILTIN"},
+		{"type(if (false) { 1 })", "NULL"},
+		{"type()", errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
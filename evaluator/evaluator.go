@@ -2,6 +2,7 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/dominicgaliano/interpreter-demo/ast"
 	"github.com/dominicgaliano/interpreter-demo/object"
@@ -17,7 +18,22 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
+// Eval evaluates node in env, reporting to the tracer installed on env if
+// any.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	tracer := env.Tracer()
+	if tracer == nil {
+		return eval(node, env)
+	}
+
+	tracer.Enter(node)
+	result := eval(node, env)
+	tracer.Exit(node, result)
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
@@ -28,38 +44,88 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: NULL}
+		}
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if stopsEvaluation(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if stopsEvaluation(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if err := bind(env, node.Name.Value, val); err != nil {
+			return err
+		}
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.DestructuringStatement:
+		val := Eval(node.Value, env)
+		if stopsEvaluation(val) {
+			return val
+		}
+		if err := evalDestructuring(node.Pattern, val, env); err != nil {
+			return err
+		}
 
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && stopsEvaluation(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if stopsEvaluation(left) {
+			return left
+		}
+
+		index := Eval(node.Index, env)
+		if stopsEvaluation(index) {
+			return index
+		}
+
+		return evalIndexExpression(left, index)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if stopsEvaluation(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
+		// the right side of a pipe is not a plain value, see evalPipeExpression
+		if node.Operator == token.PIPE {
+			return evalPipeExpression(node, env)
+		}
+		// nor is the right side of && or ||, it may never be evaluated
+		if node.Operator == token.AND || node.Operator == token.OR {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if stopsEvaluation(left) {
 			return left
 		}
 
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if stopsEvaluation(right) {
 			return right
 		}
 
@@ -76,15 +142,15 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Function{Parameters: params, Body: body, Env: env}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if stopsEvaluation(function) {
 			return function
 		}
 		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		if len(args) == 1 && stopsEvaluation(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		return applyFunction(function, args, env)
 	}
 
 	return nil
@@ -104,6 +170,8 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 			return result.Value
 		case *object.Error:
 			return result
+		case *object.Exit:
+			return result
 
 		}
 	}
@@ -131,17 +199,85 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 		//     return 0;
 		// }
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
-				return result
-			}
+		if endsBlock(result) {
+			return result
 		}
 	}
 
 	return result
 }
 
+// endsBlock reports whether result stops the rest of a block from being
+// evaluated, a return or anything that stops evaluation.
+func endsBlock(result object.Object) bool {
+	if result != nil && result.Type() == object.RETURN_VALUE_OBJ {
+		return true
+	}
+	return stopsEvaluation(result)
+}
+
+// blankIdentifier is the throwaway name. Binding a value to it stores
+// nothing, so it can be bound any number of times without colliding and
+// never shadows a real variable.
+const blankIdentifier = "_"
+
+// bind sets name to val in env, unless name is the blank identifier. It
+// returns an error if env is frozen, otherwise nil.
+func bind(env *object.Environment, name string, val object.Object) *object.Error {
+	if name == blankIdentifier {
+		return nil
+	}
+
+	if err, ok := env.Set(name, val).(*object.Error); ok {
+		return err
+	}
+	return nil
+}
+
+// evalDestructuring binds the names in pattern to the matching parts of
+// val, returning an error if val does not fit the pattern.
+func evalDestructuring(
+	pattern ast.Pattern,
+	val object.Object,
+	env *object.Environment,
+) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		array, ok := val.(*object.Array)
+		if !ok {
+			return newError("cannot destructure %s as ARRAY", val.Type())
+		}
+		if len(array.Elements) != len(pattern.Elements) {
+			return newError("wrong number of values to destructure. got=%d, want=%d",
+				len(array.Elements), len(pattern.Elements))
+		}
+
+		for i, name := range pattern.Elements {
+			if err := bind(env, name.Value, array.Elements[i]); err != nil {
+				return err
+			}
+		}
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return newError("cannot destructure %s as HASH", val.Type())
+		}
+
+		for _, name := range pattern.Keys {
+			key := (&object.String{Value: name.Value}).HashKey()
+			pair, ok := hash.Pairs[key]
+			if !ok {
+				return newError("key not found in hash: %s", name.Value)
+			}
+			if err := bind(env, name.Value, pair.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -174,6 +310,10 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	if float, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -float.Value}
+	}
+
 	if right.Type() != object.INTEGER_OBJ {
 		return newError("unknown operator: -%s", right.Type())
 	}
@@ -190,6 +330,11 @@ func evalInfixExpression(
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalInfixIntegerExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		// an integer mixed with a float is promoted to a float
+		return evalInfixFloatExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	// The following equality checks are only intended for comparing object.BOOLEAN_OBJ
 	// All other comparisons with return False
 	case operator == token.EQ:
@@ -217,7 +362,52 @@ func evalInfixIntegerExpression(operator string, left, right object.Object) obje
 	case token.ASTERISK:
 		return &object.Integer{Value: leftValue * rightValue}
 	case token.SLASH:
+		// dividing by zero would panic, ending the whole session
+		if rightValue == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftValue / rightValue}
+	case token.PERCENT:
+		// the result takes the sign of the dividend, -7 % 3 is -1
+		if rightValue == 0 {
+			return newError("division by zero")
+		}
+		return &object.Integer{Value: leftValue % rightValue}
+	case token.GT:
+		return nativeBoolToBooleanObject(leftValue > rightValue)
+	case token.LT:
+		return nativeBoolToBooleanObject(leftValue < rightValue)
+	case token.EQ:
+		return nativeBoolToBooleanObject(leftValue == rightValue)
+	case token.NOT_EQ:
+		return nativeBoolToBooleanObject(leftValue != rightValue)
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func evalInfixFloatExpression(operator string, left, right object.Object) object.Object {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	switch operator {
+	case token.PLUS:
+		return newFloat(leftValue+rightValue, left, operator, right)
+	case token.MINUS:
+		return newFloat(leftValue-rightValue, left, operator, right)
+	case token.ASTERISK:
+		return newFloat(leftValue*rightValue, left, operator, right)
+	case token.SLASH:
+		if rightValue == 0 {
+			return newError("division by zero")
+		}
+		return newFloat(leftValue/rightValue, left, operator, right)
+	case token.PERCENT:
+		if rightValue == 0 {
+			return newError("division by zero")
+		}
+		return newFloat(math.Mod(leftValue, rightValue), left, operator, right)
 	case token.GT:
 		return nativeBoolToBooleanObject(leftValue > rightValue)
 	case token.LT:
@@ -232,6 +422,121 @@ func evalInfixIntegerExpression(operator string, left, right object.Object) obje
 	}
 }
 
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch operator {
+	case token.PLUS:
+		return &object.String{Value: leftValue + rightValue}
+	case token.EQ:
+		return nativeBoolToBooleanObject(leftValue == rightValue)
+	case token.NOT_EQ:
+		return nativeBoolToBooleanObject(leftValue != rightValue)
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+// newFloat wraps the result of a float operation, or returns an error if it
+// is NaN or infinite, which object.Float never holds. As no Float is ever
+// NaN, comparisons between them behave as they do for integers.
+func newFloat(value float64, left object.Object, operator string, right object.Object) object.Object {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return newError("float overflow: %s %s %s",
+			left.Inspect(), operator, right.Inspect())
+	}
+	return &object.Float{Value: value}
+}
+
+// isNumber reports whether obj is an integer or a float.
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat returns the value of an integer or float as a float64.
+func toFloat(obj object.Object) float64 {
+	if integer, ok := obj.(*object.Integer); ok {
+		return float64(integer.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
+// evalPipeExpression applies the function on the right to the value on the
+// left, so `x |> f` is f(x). When the right side is a call, the value is
+// passed as its first argument: `x |> add(2)` is add(x, 2). To pipe into a
+// function returned by a call, bind it to a name first.
+func evalPipeExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if stopsEvaluation(left) {
+		return left
+	}
+
+	fnNode := node.Right
+	argNodes := []ast.Expression{}
+	if call, ok := node.Right.(*ast.CallExpression); ok {
+		fnNode = call.Function
+		argNodes = call.Arguments
+	}
+
+	function := Eval(fnNode, env)
+	if stopsEvaluation(function) {
+		return function
+	}
+
+	args := evalExpressions(argNodes, env)
+	if len(args) == 1 && stopsEvaluation(args[0]) {
+		return args[0]
+	}
+
+	return applyFunction(function, append([]object.Object{left}, args...), env)
+}
+
+// evalLogicalExpression evaluates `a && b` and `a || b`, only evaluating b
+// when a does not already decide the result. The result is always a
+// boolean, by the truthiness of the operands.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if stopsEvaluation(left) {
+		return left
+	}
+
+	if node.Operator == token.AND && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == token.OR && isTruthy(left) {
+		return TRUE
+	}
+
+	right := Eval(node.Right, env)
+	if stopsEvaluation(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
+// evalWhileStatement evaluates the body of the loop in env for as long as
+// its condition is truthy. A loop has no value of its own, it evaluates to
+// NULL unless the body returns, errors or exits, which ends the loop and is
+// passed on to the enclosing block.
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if stopsEvaluation(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		if result := Eval(node.Body, env); endsBlock(result) {
+			return result
+		}
+	}
+}
+
 func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
 	// determine if node.Condition evaluates to a truthy value
 	// if it does, evaluate and return node.Consequence
@@ -239,7 +544,7 @@ func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Ob
 	// if node.Alternative is not null, eval and return node.Alternative
 
 	condition := Eval(node.Condition, env)
-	if isError(condition) {
+	if stopsEvaluation(condition) {
 		return condition
 	}
 
@@ -257,7 +562,7 @@ func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Ob
 func isTruthy(obj object.Object) bool {
 	// Returns true if an object is "truthy"
 	// All objects are truthy expect the following:
-	// FALSE, NULL, INTERGER_OBJ with value = 0
+	// FALSE, NULL, INTERGER_OBJ and FLOAT_OBJ with value = 0
 
 	switch obj.Type() {
 	case object.BOOLEAN_OBJ:
@@ -267,6 +572,8 @@ func isTruthy(obj object.Object) bool {
 	case object.INTEGER_OBJ:
 		int_obj := obj.(*object.Integer)
 		return int_obj.Value != 0
+	case object.FLOAT_OBJ:
+		return obj.(*object.Float).Value != 0
 	default:
 		return true
 	}
@@ -283,54 +590,94 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// stopsEvaluation reports whether obj must stop evaluation and be passed up
+// as is. Besides errors this includes exit, which unwinds the same way.
+func stopsEvaluation(obj object.Object) bool {
+	return isError(obj) || (obj != nil && obj.Type() == object.EXIT_OBJ)
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(node.Value)
-	if !ok {
-		return newError("identifier not found: " + node.Value)
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	// fall back to builtins, so user definitions may shadow them
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
 	}
-	return val
+
+	return newError("identifier not found: " + node.Value)
 }
 
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	result := []object.Object{}
 	for _, exp := range exps {
+		// spread elements contribute every element of an array
+		spread, isSpread := exp.(*ast.SpreadElement)
+		if isSpread {
+			exp = spread.Right
+		}
+
 		evaluated := Eval(exp, env)
-		if isError(evaluated) {
+		if stopsEvaluation(evaluated) {
 			return []object.Object{evaluated}
 		}
-		result = append(result, evaluated)
+
+		if !isSpread {
+			result = append(result, evaluated)
+			continue
+		}
+
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			return []object.Object{newError("cannot spread %s, expected ARRAY",
+				evaluated.Type())}
+		}
+		result = append(result, array.Elements...)
 	}
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	function, ok := fn.(*object.Function)
-	if !ok {
-		return newError("not a function: %s", fn.Type())
-	}
+// applyFunction calls fn with args on behalf of code evaluated in env.
+func applyFunction(
+	fn object.Object,
+	args []object.Object,
+	env *object.Environment,
+) object.Object {
+	switch function := fn.(type) {
+	case *object.Function:
+		// raise an error if not enough arguments are passed
+		// allows for more arguments than defined to be passed and ignored
+		if len(function.Parameters) > len(args) {
+			return newError("function call missing arguments, got=%d, expected=%d",
+				len(args),
+				len(function.Parameters))
+		}
 
-	// raise an error if not enough arguments are passed
-	// allows for more arguments than defined to be passed and ignored
-	if len(function.Parameters) > len(args) {
-		return newError("function call missing arguments, got=%d, expected=%d",
-			len(args),
-			len(function.Parameters))
+		extendedEnv := extendFunctionEnv(function, args, env)
+		evaluated := Eval(function.Body, extendedEnv)
+		return unwrapReturnValue(evaluated)
+	case *object.Builtin:
+		return function.Fn(env, args...)
+	default:
+		return newError("not a function: %s", fn.Type())
 	}
-
-	extendedEnv := extendFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendedEnv)
-	return unwrapReturnValue(evaluated)
 }
 
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
+	caller *object.Environment,
 ) *object.Environment {
 	env := object.NewEnclosedEnviroment(fn.Env)
+	// the output and tracer belong to the evaluation, not to where fn was
+	// defined
+	env.SetOutput(caller.Output())
+	env.SetTracer(caller.Tracer())
 
 	// Set function parameter values in new environment
 	for paramId, param := range fn.Parameters {
-		env.Set(param.Value, args[paramId])
+		bind(env, param.Value, args[paramId])
 	}
 
 	return env
@@ -344,3 +691,144 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	// allows for implicit return
 	return obj
 }
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair)
+
+	for keyNode, valueNode := range node.Pairs {
+		key := Eval(keyNode, env)
+		if stopsEvaluation(key) {
+			return key
+		}
+
+		hashKey, ok := object.HashKeyOf(key)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(valueNode, env)
+		if stopsEvaluation(value) {
+			return value
+		}
+
+		pairs[hashKey] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+// evalArrayIndexExpression returns the element at index, or NULL when the
+// index is out of range. Negative indexes count back from the end.
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	arrayObject := array.(*object.Array)
+
+	idx, ok := resolveIndex(index.(*object.Integer).Value, len(arrayObject.Elements))
+	if !ok {
+		return NULL
+	}
+
+	return arrayObject.Elements[idx]
+}
+
+// evalStringIndexExpression returns the character at index as a string,
+// counting by rune, or NULL when the index is out of range. Negative
+// indexes count back from the end.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+
+	idx, ok := resolveIndex(index.(*object.Integer).Value, len(runes))
+	if !ok {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+// resolveIndex maps index into a sequence of the given length, where -1 is
+// the last element, and reports whether the result is in range.
+func resolveIndex(index int64, length int) (int64, bool) {
+	if index < 0 {
+		index += int64(length)
+	}
+
+	if index < 0 || index >= int64(length) {
+		return 0, false
+	}
+
+	return index, true
+}
+
+// evalHashIndexExpression returns the value stored under index, or NULL
+// when the key is absent.
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := object.HashKeyOf(index)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key]
+	if !ok {
+		return NULL
+	}
+
+	return pair.Value
+}
+
+// matchTypeNames maps the type names usable in match arms to object types.
+var matchTypeNames = map[string]object.ObjectType{
+	"int":      object.INTEGER_OBJ,
+	"integer":  object.INTEGER_OBJ,
+	"float":    object.FLOAT_OBJ,
+	"bool":     object.BOOLEAN_OBJ,
+	"boolean":  object.BOOLEAN_OBJ,
+	"string":   object.STRING_OBJ,
+	"array":    object.ARRAY_OBJ,
+	"hash":     object.HASH_OBJ,
+	"fn":       object.FUNCTION_OBJ,
+	"function": object.FUNCTION_OBJ,
+	"builtin":  object.BUILTIN_OBJ,
+	"null":     object.NULL_OBJ,
+}
+
+// evalMatchExpression evaluates the body of the first arm naming the type of
+// the subject, or of the first wildcard arm. NULL is returned if no arm
+// matches.
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if stopsEvaluation(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		name := arm.Pattern.Value
+
+		if name != "_" {
+			armType, ok := matchTypeNames[name]
+			if !ok {
+				return newError("unknown type in match arm: %s", name)
+			}
+			if armType != subject.Type() {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, env)
+	}
+
+	return NULL
+}
@@ -61,6 +61,21 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -190,6 +205,20 @@ f(10);`,
 	}
 }
 
+func TestBareReturnStatements(t *testing.T) {
+	tests := []string{
+		"return;",
+		"return; 9;",
+		"fn() { return; 9 }()",
+		"fn() { return }()",
+		"fn(x) { if (x) { return; } 9 }(true)",
+	}
+
+	for _, input := range tests {
+		testNullObject(t, testEval(input))
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input           string
@@ -236,6 +265,9 @@ if (10 > 1) {
 			"unknown operator: BOOLEAN + BOOLEAN",
 		},
 		{"foobar", "identifier not found: foobar"},
+		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{`"a" * "b"`, "unknown operator: STRING * STRING"},
+		{`"a" + 1`, "type mismatch: STRING + INTEGER"},
 	}
 
 	for i, tt := range tests {
@@ -323,3 +355,701 @@ func TestClosures(t *testing.T) {
 
     testIntegerObject(t, testEval(input), 4)
 }
+
+func TestEvalExpressionsStopsAtFirstError(t *testing.T) {
+	tests := []string{
+		"fn(a, b, c) { a }(record(1), 1 + true, record(3))",
+		"[record(1), -true, record(3)]",
+	}
+
+	for _, input := range tests {
+		calls := 0
+		env := object.NewEnvironment()
+		env.Set("record", &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			calls++
+			return args[0]
+		}})
+
+		program := parser.New(lexer.New(input)).ParseProgram()
+		evaluated := Eval(program, env)
+
+		if _, ok := evaluated.(*object.Error); !ok {
+			t.Errorf("object is not Error for %q. got=%T (%+v)", input, evaluated, evaluated)
+		}
+		// only the first argument is evaluated, the third never is
+		if calls != 1 {
+			t.Errorf("wrong number of arguments evaluated for %q. got=%d, want=1",
+				input, calls)
+		}
+	}
+}
+
+func TestFrozenEnvironment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"answer", 42},
+		{"let answer = 1;", errorMessage("cannot set answer, environment is frozen")},
+		{"let [a, b] = [1, 2];", errorMessage("cannot set a, environment is frozen")},
+		{"let _ = 1; answer", 42},
+		// function calls get their own environment
+		{"fn(answer) { answer }(1)", 1},
+		{"fn() { let answer = 2; answer }()", 2},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("answer", &object.Integer{Value: 42})
+		env.Freeze()
+
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		testObject(t, Eval(program, env), tt.expected)
+	}
+}
+
+func TestClosuresCaptureEnvironment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let newAdder = fn(x){ fn(y){ x + y } }; let addTwo = newAdder(2); addTwo(3);", 5},
+		// each call creates a new environment for the closure to capture
+		{"let newAdder = fn(x){ fn(y){ x + y } }; let addOne = newAdder(1); let addTen = newAdder(10); addOne(1) + addTen(1);", 13},
+		// closures see the environment, not a copy of it
+		{"let x = 1; let f = fn() { x }; let x = 2; f();", 2},
+		// parameters shadow captured names without changing them
+		{"let x = 1; let f = fn(x) { x }; f(5) + x;", 6},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStringLiteral(t *testing.T) {
+	input := `"Hello World!"`
+
+	testStringObject(t, testEval(input), "Hello World!")
+}
+
+func TestStringLiteralRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"foo";`, "foo"},
+		{`"";`, ""},
+		{`let s = "bound"; s`, "bound"},
+		{`fn(s) { s }("passed")`, "passed"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testStringObject(t, evaluated, tt.expected)
+
+		// Inspect shows the contents without quotes, as the REPL prints them
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect. got=%q, want=%q", evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`"" + ""`, ""},
+		{`let greeting = "Hello"; greeting + ", " + "there"`, "Hello, there"},
+		{`fn(a, b) { a + b }("foo", "bar")`, "foobar"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStringEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"" == ""`, true},
+		{`"ab" == "a" + "b"`, true},
+		{`let s = "x"; s == "x"`, true},
+		{`"1" == 1`, false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+
+		// the result is one of the shared booleans, not a new object
+		if evaluated != TRUE && evaluated != FALSE {
+			t.Errorf("%q did not return TRUE or FALSE. got=%p", tt.input, evaluated)
+		}
+	}
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String, got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q",
+			result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	testArrayObject(t, testEval(input), []int64{1, 4, 6})
+}
+
+func TestArrayLiteralElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[]", []int64{}},
+		{"let x = 2; [x, x * x]", []int64{2, 4}},
+		{"let double = fn(x) { x * 2 }; [double(1), double(2)]", []int64{2, 4}},
+		{"[1, 2 + true, 3]", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("[1, 2 * 2, 3 + 3]")
+	if evaluated.Inspect() != "[1, 4, 6]" {
+		t.Errorf("wrong Inspect. got=%q", evaluated.Inspect())
+	}
+}
+
+func testArrayObject(t *testing.T, obj object.Object, expected []int64) bool {
+	result, ok := obj.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array, got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if len(result.Elements) != len(expected) {
+		t.Fatalf("array has wrong number of elements. got=%d, want=%d",
+			len(result.Elements), len(expected))
+		return false
+	}
+
+	for i, el := range expected {
+		if !testIntegerObject(t, result.Elements[i], el) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error, got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. got=%q, want=%q",
+			errObj.Message, expected)
+		return false
+	}
+
+	return true
+}
+
+// errorMessage marks an expected value in table tests as the message of an
+// *object.Error rather than the value of an *object.String.
+type errorMessage string
+
+// testObject asserts obj matches expected, dispatching on the type of the
+// expected value. A nil expected value asserts NULL.
+func testObject(t *testing.T, obj object.Object, expected interface{}) bool {
+	switch expected := expected.(type) {
+	case int:
+		return testIntegerObject(t, obj, int64(expected))
+	case int64:
+		return testIntegerObject(t, obj, expected)
+	case float64:
+		return testFloatObject(t, obj, expected)
+	case bool:
+		return testBooleanObject(t, obj, expected)
+	case string:
+		return testStringObject(t, obj, expected)
+	case []int64:
+		return testArrayObject(t, obj, expected)
+	case errorMessage:
+		return testErrorObject(t, obj, string(expected))
+	case nil:
+		return testNullObject(t, obj)
+	default:
+		t.Fatalf("type of expected not handled. got=%T", expected)
+		return false
+	}
+}
+
+func TestFloatExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1.5", 1.5},
+		{"-2.25", -2.25},
+		{"0.1 + 0.2 > 0.3", true},
+		{"1.5 * 2.0", 3.0},
+		{"1.5 * 2", 3.0},
+		{"3 / 2.0", 1.5},
+		{"3 / 2", 1},
+		{"1 - 0.5", 0.5},
+		{"2.0 == 2", true},
+		{"2.5 != 2", true},
+		{"1 < 1.5", true},
+		{"if (0.0) { 1 } else { 2 }", 2},
+		{"if (0.5) { 1 } else { 2 }", 1},
+		{"match 1.5 { float => true, int => false }", true},
+		{"1.5 + true", errorMessage("type mismatch: FLOAT + BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestIntegerDivisionByZero(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"5 / 0", errorMessage("division by zero")},
+		{"0 / 0", errorMessage("division by zero")},
+		{"let zero = 1 - 1; 10 / zero", errorMessage("division by zero")},
+		{"let f = fn(x) { 10 / x }; f(0); 1", errorMessage("division by zero")},
+		{"if (5 / 0 > 1) { 1 } else { 2 }", errorMessage("division by zero")},
+		{"5 / 1", 5},
+		{"-5 / 2", -2},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestModuloExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"10 % 3", 1},
+		{"9 % 3", 0},
+		{"2 % 5", 2},
+		{"1 + 10 % 3 * 2", 3},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"-7 % -3", -1},
+		{"7.5 % 2", 1.5},
+		{"-7.5 % 2", -1.5},
+		{"10 % 0", errorMessage("division by zero")},
+		{"let zero = 0; 10 % zero", errorMessage("division by zero")},
+		{"10.0 % 0", errorMessage("division by zero")},
+		{"true % 2", errorMessage("type mismatch: BOOLEAN % INTEGER")},
+		{`"a" % "b"`, errorMessage("unknown operator: STRING % STRING")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFloatNaNAndInfinity(t *testing.T) {
+	pow := `let pow = fn(x, n) { if (n == 0) { 1.0 } else { x * pow(x, n - 1) } };`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0.0 / 0.0", errorMessage("division by zero")},
+		{"1.0 / 0.0", errorMessage("division by zero")},
+		{"-1.0 / 0.0", errorMessage("division by zero")},
+		{"1 / 0.0", errorMessage("division by zero")},
+		{"1.0 / 0", errorMessage("division by zero")},
+		{"1.0 / -0.0", errorMessage("division by zero")},
+		{"0.0 / 0.0 == 0.0 / 0.0", errorMessage("division by zero")},
+		{"1.0 / 0.0 > 1", errorMessage("division by zero")},
+		{"if (1.0 / 0.0 < 1) { 1 } else { 2 }", errorMessage("division by zero")},
+		{pow + "pow(2.0, 1023)", 8.98846567431158e+307},
+		{pow + "pow(2.0, 1024)", errorMessage("float overflow: 2.0 * 8.98846567431158e+307")},
+		{pow + "-pow(2.0, 1023) * 2", errorMessage("float overflow: -8.98846567431158e+307 * 2")},
+		{pow + "pow(2.0, 1023) + pow(2.0, 1023)",
+			errorMessage("float overflow: 8.98846567431158e+307 + 8.98846567431158e+307")},
+		{"0.0 / 1.0", 0.0},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5", "1.5"},
+		{"2.0", "2.0"},
+		{"1.5 * 2", "3.0"},
+		{"-0.25", "-0.25"},
+		{"10000000000.0 * 10000000000.0", "1e+20"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect for %q. got=%q, want=%q",
+				tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+{
+  "one": 10 - 9,
+  two: 1 + 1,
+  "three": 6 / 2,
+  4: 4,
+  true: 5,
+  false: 6
+}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{5: 5}["5"]`, nil},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{false: 5}[true]`, nil},
+		{`{"a": {"b": 1}}["a"]["b"]`, 1},
+		// arrays are only unusable when an element is
+		{`{"foo": 5}[[1]]`, nil},
+		{`{"foo": 5}[fn(x) { x }]`, errorMessage("unusable as hash key: FUNCTION")},
+		{`{"foo": 5}[[fn(x) { x }]]`, errorMessage("unusable as hash key: ARRAY")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestUnusableHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{fn(x) { x }: 1}", "unusable as hash key: FUNCTION"},
+		{"{{}: 1}", "unusable as hash key: HASH"},
+		{"{1.5: 1}", "unusable as hash key: FLOAT"},
+		{"{1: 1}[fn(x) { x }]", "unusable as hash key: FUNCTION"},
+		{`{"a": 1, len: 2}`, "unusable as hash key: BUILTIN"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArrayHashKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"{[1, 2]: 5}[[1, 2]]", 5},
+		{"let key = [1, 2]; let h = {key: 5}; h[[1, 1 + 1]]", 5},
+		{"{[1, 2]: 5}[[2, 1]]", nil},
+		{"{[1, [true, 3]]: 5}[[1, [true, 3]]]", 5},
+		{"{[]: 5}[[]]", 5},
+		{"{[1, fn(x) { x }]: 5}", errorMessage("unusable as hash key: ARRAY")},
+		{"{1: 5}[[fn(x) { x }]]", errorMessage("unusable as hash key: ARRAY")},
+		{"{fn(x) { x }: 5}", errorMessage("unusable as hash key: FUNCTION")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match 5 { int => "integer", string => "string", _ => "other" }`, "integer"},
+		{`match "hi" { int => "integer", string => "string", _ => "other" }`, "string"},
+		{`match [1] { int => "integer", string => "string", _ => "other" }`, "other"},
+		{`match true { int => 1, string => 2 }`, nil},
+		{`match fn(x) { x } { fn => 1, _ => 2 }`, 1},
+		{`match {"a": 1} { hash => 1, _ => 2 }`, 1},
+		{`match 1 + 1 { _ => 1, int => 2 }`, 1},
+		{`let x = 3; match x { int => x * 2, }`, 6},
+		{`let describe = fn(v) { match v { int => v + 1, string => v } };
+describe(1)`, 2},
+		{`match 5 { number => 1 }`, errorMessage("unknown type in match arm: number")},
+		{`match -true { _ => 1 }`, errorMessage("unknown operator: -BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+		{"[[1, 2], [3, 4]][1][0]", 3},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][100]", nil},
+		{"[][0]", nil},
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-4]", nil},
+		{`[1, 2, 3]["a"]`, errorMessage("index operator not supported: ARRAY")},
+		{"1[0]", errorMessage("index operator not supported: INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestNegativeIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", nil},
+		{"[][-1]", nil},
+		{`"abc"[0]`, "a"},
+		{`"abc"[-2]`, "b"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-4]`, nil},
+		{`"héllo"[1]`, "é"},
+		{`"世界"[-1]`, "界"},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestPipeExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let double = fn(x) { x * 2 }; 5 |> double", 10},
+		{"let add = fn(a, b) { a + b }; 5 |> add(2)", 7},
+		{"let add = fn(a, b) { a + b }; let double = fn(x) { x * 2 }; 1 |> add(2) |> double", 6},
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(3)", 7},
+		{"let add = fn(a, b) { a + b }; let addTwo = partial(add, 2); 5 |> addTwo", 7},
+		{"[1, 2, 3] |> reverse", []int64{3, 2, 1}},
+		{"1 + 2 |> fn(x) { x * 10 }", 30},
+		{"5 |> fn(x) { x + true }", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"-true |> reverse", errorMessage("unknown operator: -BOOLEAN")},
+		{"5 |> 6", errorMessage("not a function: INTEGER")},
+		{"5 |> missing(1)", errorMessage("identifier not found: missing")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || false", false},
+		{"false || true", true},
+		{"true || false", true},
+		{"1 && \"a\"", true},
+		{"0 || 0.0", false},
+		{"1 < 2 && 2 < 3", true},
+		{"false || false || true", true},
+		{"true && false || true", true},
+		{"true && 1 + true", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"-true || true", errorMessage("unknown operator: -BOOLEAN")},
+		// the right side is skipped when the left side decides the result
+		{"false && missing()", false},
+		{"true || missing()", true},
+		{"0 && 1 / 0", false},
+		{"let x = 1; let f = fn() { x + true }; true || f()", true},
+		{"false || missing()", errorMessage("identifier not found: missing")},
+		{"true && missing()", errorMessage("identifier not found: missing")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 10) { let i = i + 1; }; i", 10},
+		{"let i = 0; let sum = 0; while (i < 5) { let i = i + 1; let sum = sum + i; } sum", 15},
+		{"let i = 0; while (false) { let i = 1; }; i", 0},
+		{"while (false) { 1 }", nil},
+		{"let i = 3; while (i) { let i = i - 1; }; i", 0},
+		// a return in the body ends the loop and the enclosing function
+		{"let f = fn() { let i = 0; while (true) { if (i == 3) { return i; } let i = i + 1; } }; f()", 3},
+		{"let i = 0; while (true) { let i = i + 1; if (i > 4) { return i * 10; } }; 1", 50},
+		{"while (true) { 1 + true }", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"while (missing) { 1 }", errorMessage("identifier not found: missing")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("let i = 0; while (true) { let i = i + 1; if (i == 2) { exit(i) } }")
+	if exit, ok := evaluated.(*object.Exit); !ok || exit.Code != 2 {
+		t.Errorf("exit did not end the loop. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestBlankIdentifier(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; let _ = 2; let _ = 3; x", 1},
+		{"let _ = 2; _", errorMessage("identifier not found: _")},
+		{"let _ = -true; 1", errorMessage("unknown operator: -BOOLEAN")},
+		{"let second = fn(_, y) { y }; second(1, 2)", 2},
+		{"let f = fn(_, _, z) { z }; f(1, 2, 3)", 3},
+		{"let f = fn(_) { _ }; f(1)", errorMessage("identifier not found: _")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDestructuringStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let pair = fn() { [3, 4] }; let [a, b] = pair(); b - a", 1},
+		{"let [] = []; 1", 1},
+		{"let [_, b, _] = [1, 2, 3]; b", 2},
+		{`let {x, y} = {"x": 5, "y": 6, "z": 7}; x * y`, 30},
+		{`let point = {"x": 1}; let {x} = point; x`, 1},
+		{"let [a, b] = [1]", errorMessage("wrong number of values to destructure. got=1, want=2")},
+		{"let [a] = [1, 2]", errorMessage("wrong number of values to destructure. got=2, want=1")},
+		{`let {x, y} = {"x": 5}`, errorMessage("key not found in hash: y")},
+		{"let [a] = 1", errorMessage("cannot destructure INTEGER as ARRAY")},
+		{"let {a} = [1]", errorMessage("cannot destructure ARRAY as HASH")},
+		{"let [a] = [-true]", errorMessage("unknown operator: -BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestSpreadElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(a, b) { a + b }; let pair = [1, 2]; add(...pair)", 3},
+		{"let add = fn(a, b, c) { a + b + c }; add(1, ...[2, 3])", 6},
+		{"let add = fn(a, b, c) { a + b + c }; add(...[1], 2, ...[3])", 6},
+		{"let rest = [1, 2]; [0, ...rest, 9]", []int64{0, 1, 2, 9}},
+		{"let rest = [1, 2]; [...rest, 9]", []int64{1, 2, 9}},
+		{"let rest = [1, 2]; [0, ...rest]", []int64{0, 1, 2}},
+		{"[...[], ...[1], ...[]]", []int64{1}},
+		{"let add = fn(a, b) { a + b }; 1 |> add(...[2])", 3},
+		{"[...5]", errorMessage("cannot spread INTEGER, expected ARRAY")},
+		{"reverse(...1)", errorMessage("cannot spread INTEGER, expected ARRAY")},
+		{"[...[-true]]", errorMessage("unknown operator: -BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
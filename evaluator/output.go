@@ -0,0 +1,17 @@
+package evaluator
+
+import (
+	"io"
+	"os"
+
+	"github.com/dominicgaliano/interpreter-demo/object"
+)
+
+// output returns where programs evaluated in env print, stdout unless the
+// host set a writer with Environment.SetOutput.
+func output(env *object.Environment) io.Writer {
+	if out := env.Output(); out != nil {
+		return out
+	}
+	return os.Stdout
+}
@@ -0,0 +1,46 @@
+package evaluator
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+
+	"github.com/dominicgaliano/interpreter-demo/lexer"
+	"github.com/dominicgaliano/interpreter-demo/object"
+	"github.com/dominicgaliano/interpreter-demo/parser"
+)
+
+// preludeSource is the part of the standard library written in Monkey
+// itself, such as map, filter and reduce.
+//
+//go:embed prelude.monkey
+var preludeSource string
+
+var (
+	prelude     *object.Environment
+	preludeOnce sync.Once
+)
+
+// Prelude returns the environment holding the functions defined by the
+// prelude. It is evaluated on first use and shared afterwards, so it is
+// frozen: programs should run in an environment enclosed by it, ex.
+// object.NewEnclosedEnviroment(evaluator.Prelude()).
+func Prelude() *object.Environment {
+	preludeOnce.Do(func() {
+		prelude = object.NewEnvironment()
+
+		p := parser.New(lexer.New(preludeSource))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			panic("prelude does not parse: " + strings.Join(p.Errors(), "; "))
+		}
+
+		if result := Eval(program, prelude); stopsEvaluation(result) {
+			panic("prelude does not evaluate: " + result.Inspect())
+		}
+
+		prelude.Freeze()
+	})
+
+	return prelude
+}
@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/dominicgaliano/interpreter-demo/lexer"
+	"github.com/dominicgaliano/interpreter-demo/object"
+	"github.com/dominicgaliano/interpreter-demo/parser"
+)
+
+func TestPrelude(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"map([1, 2, 3], fn(x) { x * 2 })", []int64{2, 4, 6}},
+		{"map([], fn(x) { x })", []int64{}},
+		{"filter([1, 2, 3, 4], fn(x) { x > 2 })", []int64{3, 4}},
+		{"filter([[1], [2]], fn(x) { true })[1]", []int64{2}},
+		{"reduce([1, 2, 3], 10, fn(acc, x) { acc - x })", 4},
+		{"reduce([], 10, fn(acc, x) { acc - x })", 10},
+		{"sum([1, 2, 3, 4])", 10},
+		// reduce loops rather than recursing, so long arrays do not exhaust
+		// the stack
+		{"sum(times(1000000, fn(i) { i }))", 499999500000},
+		{"reduce([1, 2], 0, fn(acc, x) { if (x == 2) { return 10 } acc + x })", 10},
+		{"reduce([1, 2], 0, fn(acc, x) { acc + true })", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"[1, 2, 3] |> map(fn(x) { x * x }) |> sum", 14},
+		{"map([1], fn(x) { x + true })", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		// programs can shadow prelude names without affecting the prelude
+		{"let map = 5; map", 5},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnclosedEnviroment(Prelude())
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		testObject(t, Eval(program, env), tt.expected)
+	}
+
+	if _, ok := Prelude().Get("map"); !ok {
+		t.Errorf("map missing from prelude")
+	}
+	if !Prelude().Frozen() {
+		t.Errorf("prelude is not frozen")
+	}
+	if Prelude() != Prelude() {
+		t.Errorf("prelude is not cached")
+	}
+}
@@ -0,0 +1,73 @@
+package evaluator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dominicgaliano/interpreter-demo/ast"
+	"github.com/dominicgaliano/interpreter-demo/object"
+)
+
+// Profiler is an object.Tracer that tallies how many times each type of AST
+// node is evaluated and the total time spent evaluating them. Times are
+// inclusive, the time of a node includes the time of the nodes nested within
+// it.
+type Profiler struct {
+	counts    map[string]int
+	durations map[string]time.Duration
+
+	// starts holds the start time of each node currently being evaluated
+	starts []time.Time
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		counts:    make(map[string]int),
+		durations: make(map[string]time.Duration),
+	}
+}
+
+func (p *Profiler) Enter(node ast.Node) {
+	p.starts = append(p.starts, time.Now())
+}
+
+func (p *Profiler) Exit(node ast.Node, result object.Object) {
+	start := p.starts[len(p.starts)-1]
+	p.starts = p.starts[:len(p.starts)-1]
+
+	name := nodeTypeName(node)
+	p.counts[name]++
+	p.durations[name] += time.Since(start)
+}
+
+// Count returns how many times nodes of the named type were evaluated.
+// Ex. p.Count("InfixExpression")
+func (p *Profiler) Count(nodeType string) int {
+	return p.counts[nodeType]
+}
+
+// Report returns a table with a line per node type, sorted by name.
+func (p *Profiler) Report() string {
+	var out strings.Builder
+
+	names := []string{}
+	for name := range p.counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(&out, "%-24s %8s %12s\n", "NODE", "COUNT", "TIME")
+	for _, name := range names {
+		fmt.Fprintf(&out, "%-24s %8d %12s\n", name, p.counts[name], p.durations[name])
+	}
+
+	return out.String()
+}
+
+// nodeTypeName returns the name of the node's type without the package,
+// ex. "InfixExpression" for *ast.InfixExpression.
+func nodeTypeName(node ast.Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
@@ -0,0 +1,85 @@
+package evaluator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dominicgaliano/interpreter-demo/lexer"
+	"github.com/dominicgaliano/interpreter-demo/object"
+	"github.com/dominicgaliano/interpreter-demo/parser"
+)
+
+func TestProfiler(t *testing.T) {
+	profiler := NewProfiler()
+	env := object.NewEnvironment()
+	env.SetTracer(profiler)
+
+	testArrayObject(t, testEvalIn("times(3, fn(i) { i * 2 })", env), []int64{0, 2, 4})
+
+	expected := map[string]int{
+		"Program":             1,
+		"ExpressionStatement": 4, // the program's statement and one per iteration
+		"CallExpression":      1,
+		"Identifier":          4, // times, and i per iteration
+		"IntegerLiteral":      4, // 3, and 2 per iteration
+		"FunctionLiteral":     1,
+		"BlockStatement":      3,
+		"InfixExpression":     3,
+		"IfExpression":        0,
+	}
+
+	for name, count := range expected {
+		if got := profiler.Count(name); got != count {
+			t.Errorf("wrong count for %s. got=%d, want=%d", name, got, count)
+		}
+	}
+
+	report := profiler.Report()
+	lines := strings.Split(strings.TrimSpace(report), "\n")
+	if len(lines) != 9 {
+		t.Fatalf("report should have a header and 8 node lines. got=%q", report)
+	}
+	if !strings.HasPrefix(lines[1], "BlockStatement ") ||
+		!strings.HasPrefix(lines[8], "Program ") {
+		t.Errorf("report lines are not sorted by node type. got=%q", report)
+	}
+}
+
+func TestTracerDisabled(t *testing.T) {
+	profiler := NewProfiler()
+	env := object.NewEnvironment()
+	previous := env.SetTracer(profiler)
+	env.SetTracer(previous)
+
+	testEvalIn("1 + 1", env)
+
+	if got := profiler.Count("Program"); got != 0 {
+		t.Errorf("removed tracer still observed evaluation. got=%d", got)
+	}
+}
+
+func TestTracerPerEnvironment(t *testing.T) {
+	// a function defined elsewhere is traced when called from the traced
+	// environment, nothing evaluated in the other environment is
+	untraced := object.NewEnvironment()
+	testEvalIn("let double = fn(x) { x * 2 };", untraced)
+
+	profiler := NewProfiler()
+	traced := object.NewEnclosedEnviroment(untraced)
+	traced.SetTracer(profiler)
+
+	testIntegerObject(t, testEvalIn("double(1)", traced), 2)
+	testIntegerObject(t, testEvalIn("double(2)", untraced), 4)
+
+	if got := profiler.Count("InfixExpression"); got != 1 {
+		t.Errorf("wrong count for InfixExpression. got=%d, want=1", got)
+	}
+	if got := profiler.Count("Program"); got != 1 {
+		t.Errorf("wrong count for Program. got=%d, want=1", got)
+	}
+}
+
+func testEvalIn(input string, env *object.Environment) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	return Eval(program, env)
+}
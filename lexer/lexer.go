@@ -11,15 +11,37 @@ type Lexer struct {
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
 	ch           byte // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char
+
+	keepComments bool
+}
+
+// Options configures a Lexer created with NewWithOptions.
+type Options struct {
+	// KeepComments makes the lexer return comments as COMMENT tokens rather
+	// than skipping them. The parser does not expect them, so it is only
+	// useful for tools working on the token stream, such as formatters.
+	KeepComments bool
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewWithOptions(input, Options{})
+}
+
+func NewWithOptions(input string, opts Options) *Lexer {
+	l := &Lexer{input: input, line: 1, keepComments: opts.KeepComments}
 	l.readChar() // initialize Lexer state
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+
 	// set ch to ASCII NUL on end of file
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
@@ -41,15 +63,80 @@ func (l *Lexer) readIdentifier() string {
 	return builder.String()
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or float literal and returns it along with its
+// token type. A '.' only starts a fraction when a digit follows it, so
+// "1." and "1..." still end the number at the dot.
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	var builder strings.Builder
+	var tokenType token.TokenType = token.INT
 
 	for isDigit(l.ch) {
 		builder.WriteByte(l.ch)
 		l.readChar()
 	}
 
-	return builder.String()
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		builder.WriteByte(l.ch)
+		l.readChar()
+
+		for isDigit(l.ch) {
+			builder.WriteByte(l.ch)
+			l.readChar()
+		}
+	}
+
+	return builder.String(), tokenType
+}
+
+// readString reads the contents of a string literal. It is called with the
+// opening quote under examination and stops on the closing quote, or at the
+// end of the input, in which case terminated is false.
+func (l *Lexer) readString() (contents string, terminated bool) {
+	var builder strings.Builder
+
+	for {
+		l.readChar()
+		if l.ch == '"' {
+			return builder.String(), true
+		}
+		// a NUL byte within the input is part of the string
+		if l.ch == 0 && l.position >= len(l.input) {
+			return builder.String(), false
+		}
+		builder.WriteByte(l.ch)
+	}
+}
+
+// readLineComment reads a // comment, leaving the last char before the end
+// of the line under examination. The newline is not part of the comment.
+func (l *Lexer) readLineComment() string {
+	start := l.position
+
+	for l.readPosition < len(l.input) && l.input[l.readPosition] != '\n' {
+		l.readChar()
+	}
+
+	return l.input[start:l.readPosition]
+}
+
+// readBlockComment reads a /* */ comment, leaving its closing '/' under
+// examination. If the input ends first, terminated is false and the comment
+// runs to the end of the input.
+func (l *Lexer) readBlockComment() (comment string, terminated bool) {
+	start := l.position
+	l.readChar() // the '*' of the opening /*
+
+	for {
+		l.readChar()
+		if l.position >= len(l.input) {
+			return l.input[start:], false
+		}
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.readChar()
+			return l.input[start:l.readPosition], true
+		}
+	}
 }
 
 func (l *Lexer) peekChar() byte {
@@ -60,10 +147,39 @@ func (l *Lexer) peekChar() byte {
 	}
 }
 
+// peekCharAt looks n characters ahead of the current char, peekCharAt(1)
+// is equivalent to peekChar().
+func (l *Lexer) peekCharAt(n int) byte {
+	position := l.position + n
+	if position >= len(l.input) {
+		return 0
+	}
+	return l.input[position]
+}
+
+// NextToken returns the next token in the input, stamped with the line and
+// column it starts at.
+// Lexing always terminates: every call that does not return EOF consumes at
+// least one byte of input, and once the input is exhausted every call
+// returns EOF. Bytes that do not start a valid token are returned as ILLEGAL.
+// Comments are skipped unless the lexer was created with KeepComments.
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	for {
+		l.skipWhitespace()
+
+		line, column := l.line, l.column
+		tok := l.nextToken()
+		if tok.Type == token.COMMENT && !l.keepComments {
+			continue
+		}
 
-	l.skipWhitespace()
+		tok.Line, tok.Column = line, column
+		return tok
+	}
+}
+
+func (l *Lexer) nextToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -72,12 +188,19 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			tok.Literal = string(ch) + string(l.ch)
 			tok.Type = token.EQ
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok.Literal = string(ch) + string(l.ch)
+			tok.Type = token.ARROW
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -99,10 +222,60 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 
+	case '|':
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok.Literal = string(ch) + string(l.ch)
+			tok.Type = token.PIPE
+		} else if l.peekChar() == '|' {
+			ch := l.ch
+			l.readChar()
+			tok.Literal = string(ch) + string(l.ch)
+			tok.Type = token.OR
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+
+	case '&':
+		if l.peekChar() == '&' {
+			ch := l.ch
+			l.readChar()
+			tok.Literal = string(ch) + string(l.ch)
+			tok.Type = token.AND
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+
+	case '.':
+		if l.peekCharAt(1) == '.' && l.peekCharAt(2) == '.' {
+			l.readChar()
+			l.readChar()
+			tok.Literal = "..."
+			tok.Type = token.ELLIPSIS
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.peekChar() == '/' {
+			tok.Type = token.COMMENT
+			tok.Literal = l.readLineComment()
+		} else if l.peekChar() == '*' {
+			comment, terminated := l.readBlockComment()
+			if terminated {
+				tok.Type = token.COMMENT
+			} else {
+				tok.Type = token.ILLEGAL
+			}
+			tok.Literal = comment
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
 		tok = newToken(token.LT, l.ch)
 	case '>':
@@ -111,8 +284,28 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+	case '"':
+		contents, terminated := l.readString()
+		if terminated {
+			tok.Type = token.STRING
+			tok.Literal = contents
+		} else {
+			// keep the opening quote so the literal shows what went wrong
+			tok.Type = token.ILLEGAL
+			tok.Literal = `"` + contents
+		}
 	case 0:
-		tok = newToken(token.EOF, 0)
+		// ch is also 0 for a NUL byte within the input, which must not end
+		// lexing early
+		if l.position < len(l.input) {
+			tok = newToken(token.ILLEGAL, l.ch)
+		} else {
+			tok = newToken(token.EOF, 0)
+		}
 	default:
 		// token is not a special character,
 		if isLetter(l.ch) {
@@ -121,9 +314,8 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdentifier(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			// parse integer literal
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			// parse integer or float literal
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -156,10 +348,12 @@ func isWhitespace(ch byte) bool {
 }
 
 func newToken(tokenType token.TokenType, ch byte) token.Token {
-	if ch == 0 {
+	if tokenType == token.EOF {
 		return token.Token{Type: tokenType, Literal: ""}
 	}
-	return token.Token{Type: tokenType, Literal: string(ch)}
+	// convert as a byte slice, string(ch) would encode bytes >= 0x80 as the
+	// UTF-8 of the matching code point rather than the original byte
+	return token.Token{Type: tokenType, Literal: string([]byte{ch})}
 }
 
 func (l *Lexer) skipWhitespace() {
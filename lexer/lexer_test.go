@@ -61,7 +61,7 @@ x + y;
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -72,6 +72,9 @@ return false;
 
 10 == 10;
 10 != 9;
+match x { int => 1 }
+10 % 3;
+[...x]
 `
 
 	tests := []struct {
@@ -151,6 +154,21 @@ return false;
         {token.NOT_EQ, "!="},
         {token.INT, "9"},
         {token.SEMICOLON, ";"},
+		{token.MATCH, "match"},
+		{token.IDENT, "x"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "int"},
+		{token.ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.INT, "10"},
+		{token.PERCENT, "%"},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACKET, "["},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "x"},
+		{token.RBRACKET, "]"},
 		{token.EOF, ""},
 	}
 
@@ -169,3 +187,464 @@ return false;
 		}
 	}
 }
+
+func TestNextTokenNumbers(t *testing.T) {
+	input := "5 1.5 0.25 10.0 1. [1...]"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.FLOAT, "1.5"},
+		{token.FLOAT, "0.25"},
+		{token.FLOAT, "10.0"},
+		// a dot without a digit after it does not start a fraction
+		{token.INT, "1"},
+		{token.ILLEGAL, "."},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.ELLIPSIS, "..."},
+		{token.RBRACKET, "]"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype.wrong, expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal.wrong, expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenEqualityOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"==", []token.Token{{Type: token.EQ, Literal: "=="}}},
+		{"!=", []token.Token{{Type: token.NOT_EQ, Literal: "!="}}},
+		{"=", []token.Token{{Type: token.ASSIGN, Literal: "="}}},
+		{"!", []token.Token{{Type: token.BANG, Literal: "!"}}},
+		{"= =", []token.Token{
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.ASSIGN, Literal: "="},
+		}},
+		{"===", []token.Token{
+			{Type: token.EQ, Literal: "=="},
+			{Type: token.ASSIGN, Literal: "="},
+		}},
+		{"!==", []token.Token{
+			{Type: token.NOT_EQ, Literal: "!="},
+			{Type: token.ASSIGN, Literal: "="},
+		}},
+		{"!!=", []token.Token{
+			{Type: token.BANG, Literal: "!"},
+			{Type: token.NOT_EQ, Literal: "!="},
+		}},
+		{"=!", []token.Token{
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.BANG, Literal: "!"},
+		}},
+		{"a==b", []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.EQ, Literal: "=="},
+			{Type: token.IDENT, Literal: "b"},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		for i, want := range expected {
+			tok := l.NextToken()
+			if tok.Type != want.Type || tok.Literal != want.Literal {
+				t.Fatalf("%q: tokens[%d] wrong. expected=%+v, got=%+v",
+					tt.input, i, want, tok)
+			}
+		}
+	}
+}
+
+func TestNextTokenLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"&&", []token.Token{{Type: token.AND, Literal: "&&"}}},
+		{"||", []token.Token{{Type: token.OR, Literal: "||"}}},
+		{"&", []token.Token{{Type: token.ILLEGAL, Literal: "&"}}},
+		{"|", []token.Token{{Type: token.ILLEGAL, Literal: "|"}}},
+		{"& &", []token.Token{
+			{Type: token.ILLEGAL, Literal: "&"},
+			{Type: token.ILLEGAL, Literal: "&"},
+		}},
+		{"&&&", []token.Token{
+			{Type: token.AND, Literal: "&&"},
+			{Type: token.ILLEGAL, Literal: "&"},
+		}},
+		{"|||>", []token.Token{
+			{Type: token.OR, Literal: "||"},
+			{Type: token.PIPE, Literal: "|>"},
+		}},
+		{"a&&b||c", []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.AND, Literal: "&&"},
+			{Type: token.IDENT, Literal: "b"},
+			{Type: token.OR, Literal: "||"},
+			{Type: token.IDENT, Literal: "c"},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		for i, want := range expected {
+			tok := l.NextToken()
+			if tok.Type != want.Type || tok.Literal != want.Literal {
+				t.Fatalf("%q: tokens[%d] wrong. expected=%+v, got=%+v",
+					tt.input, i, want, tok)
+			}
+		}
+	}
+}
+
+func TestPeekChar(t *testing.T) {
+	l := New("ab")
+
+	if l.peekChar() != 'b' {
+		t.Errorf("peekChar wrong. got=%q, want='b'", l.peekChar())
+	}
+	if l.ch != 'a' {
+		t.Errorf("peekChar advanced the lexer. ch=%q", l.ch)
+	}
+
+	l.readChar()
+	if l.peekChar() != 0 {
+		t.Errorf("peekChar at end of input wrong. got=%q, want=0", l.peekChar())
+	}
+}
+
+func TestNextTokenPositions(t *testing.T) {
+	input := "let x = 1;\n  x + \"two\"\n\n\tfoo(x)"
+
+	tests := []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 1, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Type: token.INT, Literal: "1", Line: 1, Column: 9},
+		{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 10},
+		{Type: token.IDENT, Literal: "x", Line: 2, Column: 3},
+		{Type: token.PLUS, Literal: "+", Line: 2, Column: 5},
+		{Type: token.STRING, Literal: "two", Line: 2, Column: 7},
+		{Type: token.IDENT, Literal: "foo", Line: 4, Column: 2},
+		{Type: token.LPAREN, Literal: "(", Line: 4, Column: 5},
+		{Type: token.IDENT, Literal: "x", Line: 4, Column: 6},
+		{Type: token.RPAREN, Literal: ")", Line: 4, Column: 7},
+		{Type: token.EOF, Literal: "", Line: 4, Column: 8},
+	}
+
+	l := New(input)
+	for i, want := range tests {
+		if tok := l.NextToken(); tok != want {
+			t.Fatalf("tokens[%d] wrong. expected=%+v, got=%+v", i, want, tok)
+		}
+	}
+}
+
+func TestNextTokenStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{`"hello world"`, []token.Token{
+			{Type: token.STRING, Literal: "hello world"},
+		}},
+		{`""`, []token.Token{
+			{Type: token.STRING, Literal: ""},
+		}},
+		{`"a" "b"`, []token.Token{
+			{Type: token.STRING, Literal: "a"},
+			{Type: token.STRING, Literal: "b"},
+		}},
+		{"\"a\x00b\"", []token.Token{
+			{Type: token.STRING, Literal: "a\x00b"},
+		}},
+		{`"unterminated`, []token.Token{
+			{Type: token.ILLEGAL, Literal: `"unterminated`},
+		}},
+		{`let x = "`, []token.Token{
+			{Type: token.LET, Literal: "let"},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.ILLEGAL, Literal: `"`},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		// every input ends in EOF, which keeps being returned
+		expected := append(tt.expected,
+			token.Token{Type: token.EOF, Literal: ""},
+			token.Token{Type: token.EOF, Literal: ""})
+
+		for i, want := range expected {
+			tok := l.NextToken()
+			if tok.Type != want.Type || tok.Literal != want.Literal {
+				t.Fatalf("%q: tokens[%d] wrong. expected=%+v, got=%+v",
+					tt.input, i, want, tok)
+			}
+		}
+	}
+}
+
+func TestNextTokenComments(t *testing.T) {
+	input := `let x = 5; // five
+/* a block
+   comment */ x / 2
+// at the end`
+
+	kept := []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 1, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Type: token.INT, Literal: "5", Line: 1, Column: 9},
+		{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 10},
+		{Type: token.COMMENT, Literal: "// five", Line: 1, Column: 12},
+		{Type: token.COMMENT, Literal: "/* a block\n   comment */", Line: 2, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 3, Column: 15},
+		{Type: token.SLASH, Literal: "/", Line: 3, Column: 17},
+		{Type: token.INT, Literal: "2", Line: 3, Column: 19},
+		{Type: token.COMMENT, Literal: "// at the end", Line: 4, Column: 1},
+		{Type: token.EOF, Literal: "", Line: 4, Column: 14},
+	}
+
+	l := NewWithOptions(input, Options{KeepComments: true})
+	for i, want := range kept {
+		if tok := l.NextToken(); tok != want {
+			t.Fatalf("tokens[%d] wrong with comments kept. expected=%+v, got=%+v",
+				i, want, tok)
+		}
+	}
+
+	// by default the same input lexes as if the comments were not there
+	l = New(input)
+	for i, want := range kept {
+		if want.Type == token.COMMENT {
+			continue
+		}
+		if tok := l.NextToken(); tok != want {
+			t.Fatalf("tokens[%d] wrong with comments skipped. expected=%+v, got=%+v",
+				i, want, tok)
+		}
+	}
+}
+
+func TestLineComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"// only a comment", nil},
+		{"// only a comment\n", nil},
+		{"//", nil},
+		{"// one\n// two\n", nil},
+		{"1 // let x = 2;\n3", []token.Token{
+			{Type: token.INT, Literal: "1"},
+			{Type: token.INT, Literal: "3"},
+		}},
+		{"// commented out: let x = 1;\nlet y = 2;", []token.Token{
+			{Type: token.LET, Literal: "let"},
+			{Type: token.IDENT, Literal: "y"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.INT, Literal: "2"},
+			{Type: token.SEMICOLON, Literal: ";"},
+		}},
+		{"x//y", []token.Token{
+			{Type: token.IDENT, Literal: "x"},
+		}},
+		{`"// not a comment"`, []token.Token{
+			{Type: token.STRING, Literal: "// not a comment"},
+		}},
+		{"a / b", []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.SLASH, Literal: "/"},
+			{Type: token.IDENT, Literal: "b"},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		// a comment running to the end of the input still ends in EOF
+		expected := append(tt.expected,
+			token.Token{Type: token.EOF, Literal: ""},
+			token.Token{Type: token.EOF, Literal: ""})
+
+		for i, want := range expected {
+			tok := l.NextToken()
+			if tok.Type != want.Type || tok.Literal != want.Literal {
+				t.Fatalf("%q: tokens[%d] wrong. expected=%+v, got=%+v",
+					tt.input, i, want, tok)
+			}
+		}
+	}
+}
+
+func TestBlockComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"/* one line */", nil},
+		{"/**/", nil},
+		{"/***/", nil},
+		{"1 /* two */ 3", []token.Token{
+			{Type: token.INT, Literal: "1", Line: 1, Column: 1},
+			{Type: token.INT, Literal: "3", Line: 1, Column: 13},
+		}},
+		{"1 /* spans\nseveral\nlines */ 2\n3", []token.Token{
+			{Type: token.INT, Literal: "1", Line: 1, Column: 1},
+			{Type: token.INT, Literal: "2", Line: 3, Column: 10},
+			{Type: token.INT, Literal: "3", Line: 4, Column: 1},
+		}},
+		{"/* // line comment inside */ 1", []token.Token{
+			{Type: token.INT, Literal: "1", Line: 1, Column: 30},
+		}},
+		{"/* not /* nested */ 1", []token.Token{
+			{Type: token.INT, Literal: "1", Line: 1, Column: 21},
+		}},
+		{"1 /* unterminated\n", []token.Token{
+			{Type: token.INT, Literal: "1", Line: 1, Column: 1},
+			{Type: token.ILLEGAL, Literal: "/* unterminated\n", Line: 1, Column: 3},
+		}},
+		{"/*/", []token.Token{
+			{Type: token.ILLEGAL, Literal: "/*/", Line: 1, Column: 1},
+		}},
+		{"/* ends in a star *", []token.Token{
+			{Type: token.ILLEGAL, Literal: "/* ends in a star *", Line: 1, Column: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		for i, want := range tt.expected {
+			if tok := l.NextToken(); tok != want {
+				t.Fatalf("%q: tokens[%d] wrong. expected=%+v, got=%+v",
+					tt.input, i, want, tok)
+			}
+		}
+
+		// unterminated comments do not keep the lexer from finishing
+		for i := 0; i < 2; i++ {
+			if tok := l.NextToken(); tok.Type != token.EOF {
+				t.Fatalf("%q: expected EOF, got=%+v", tt.input, tok)
+			}
+		}
+	}
+}
+
+func TestNextTokenIllegalBytes(t *testing.T) {
+	input := "1\x002 \xc3 # .. ."
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "1"},
+		{token.ILLEGAL, "\x00"},
+		{token.INT, "2"},
+		{token.ILLEGAL, "\xc3"},
+		{token.ILLEGAL, "#"},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.EOF, ""},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype.wrong, expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal.wrong, expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// FuzzNextToken checks that lexing arbitrary input never panics, reaches
+// EOF within one token per input byte, and keeps returning EOF afterwards.
+func FuzzNextToken(f *testing.F) {
+	seeds := []string{
+		"",
+		"!",
+		"=",
+		"!=",
+		"==",
+		"=>",
+		"|",
+		"|>",
+		"&",
+		"&&",
+		"||",
+		`"`,
+		`""`,
+		`"unterminated`,
+		"\x00",
+		"a\x00b",
+		"\xff\xfe",
+		"héllo",
+		"let add = fn(x, y) { x + y; };",
+		`{"key": [1, 2]}[0]`,
+		"..",
+		"...",
+		"//",
+		"// comment\n1",
+		"/*",
+		"/*/",
+		"/**/",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := New(input)
+
+		for i := 0; ; i++ {
+			if i > len(input) {
+				t.Fatalf("no EOF after %d tokens for input %q", i, input)
+			}
+
+			if l.NextToken().Type == token.EOF {
+				break
+			}
+		}
+
+		for i := 0; i < 3; i++ {
+			if tok := l.NextToken(); tok.Type != token.EOF {
+				t.Fatalf("token after EOF for input %q. got=%q (%q)",
+					input, tok.Type, tok.Literal)
+			}
+		}
+	})
+}
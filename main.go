@@ -1,19 +1,39 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"os"
 	"os/user"
 
 	"github.com/dominicgaliano/interpreter-demo/repl"
+	"github.com/dominicgaliano/interpreter-demo/runner"
 )
 
 func main() {
+    var source string
+    var noPrelude bool
+    flag.StringVar(&source, "e", "", "evaluate `source` and exit")
+    flag.StringVar(&source, "eval", "", "evaluate `source` and exit (same as -e)")
+    flag.BoolVar(&noPrelude, "no-prelude", false, "start without map, filter, reduce, etc.")
+    flag.Parse()
+
+    opts := runner.Options{NoPrelude: noPrelude}
+
+    // monkey -e "1 + 2" evaluates a one-liner, monkey script.monkey runs a
+    // file, anything else starts the REPL
+    switch {
+    case source != "":
+        os.Exit(runner.EvalStringWithOptions(source, os.Stdout, opts))
+    case flag.NArg() > 0:
+        os.Exit(runner.RunFileWithOptions(flag.Arg(0), os.Stdout, opts))
+    }
+
     user, err := user.Current()
     if err != nil {
         panic(err)
     }
-    fmt.Printf("Welcome %s, this is the Monkey programming language!\n", user.Username)
-    fmt.Printf("Input commands below:\n")
-    repl.Start(os.Stdin, os.Stdout)
+    repl.StartWithOptions(os.Stdin, os.Stdout, repl.Options{
+        Banner:    repl.Banner(user.Username),
+        NoPrelude: noPrelude,
+    })
 }
@@ -1,5 +1,13 @@
 package object
 
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/dominicgaliano/interpreter-demo/ast"
+)
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
     return &Environment{store: s, outer: nil}
@@ -8,6 +16,8 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnviroment(outer *Environment) *Environment {
     env := NewEnvironment()
     env.outer = outer
+    env.output = outer.output
+    env.tracer = outer.tracer
     return env
 }
 
@@ -18,6 +28,16 @@ type Environment struct {
     // outer scope of the environment to create a environment that extends
     // the original outer environment to used during evaluation
     outer *Environment
+
+	// frozen environments reject Set, see Freeze
+	frozen bool
+
+	// output is where programs evaluated in the environment print, see
+	// SetOutput
+	output io.Writer
+	// tracer observes evaluation in the environment, see SetTracer. When
+	// nil, tracing costs a single comparison per node.
+	tracer Tracer
 }
 
 // Get checks the inner scope for a variable with identifier, name
@@ -32,7 +52,116 @@ func (e *Environment) Get(name string) (Object, bool) {
 }
 
 
+// Set binds name to val in this scope and returns val. If the environment
+// is frozen nothing is bound and an *Error is returned instead.
 func (e *Environment) Set(name string, val Object) Object {
+	if e.frozen {
+		return &Error{Message: fmt.Sprintf("cannot set %s, environment is frozen", name)}
+	}
+
     e.store[name] = val
     return val
 }
+
+// Assign updates the binding of name in the nearest scope defining it,
+// walking the outer scopes like Get, so a closure can change a variable of
+// the scope it was created in rather than shadowing it. It returns false,
+// binding nothing, if name is unbound or the scope defining it is frozen.
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			if env.frozen {
+				return false
+			}
+			env.store[name] = val
+			return true
+		}
+	}
+	return false
+}
+
+// Delete removes the binding of name from this scope and reports whether
+// there was one. Outer scopes are never touched, so a name they define
+// becomes visible again once an inner binding shadowing it is deleted.
+// Nothing is removed from a frozen environment.
+func (e *Environment) Delete(name string) bool {
+	if e.frozen {
+		return false
+	}
+
+	if _, ok := e.store[name]; !ok {
+		return false
+	}
+	delete(e.store, name)
+	return true
+}
+
+// Names returns every name visible from this scope, its own and those of
+// the outer scopes, sorted and each listed once even when shadowed.
+func (e *Environment) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+
+	for env := e; env != nil; env = env.outer {
+		for name := range env.store {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Freeze makes the environment read-only, every later Set fails. Names can
+// still be read, and environments enclosing it are unaffected so they can
+// define their own names, including ones that shadow frozen names.
+func (e *Environment) Freeze() {
+	e.frozen = true
+}
+
+// Frozen reports whether Freeze has been called on the environment.
+func (e *Environment) Frozen() bool {
+	return e.frozen
+}
+
+// SetOutput sets where programs evaluated in the environment print, ex. with
+// puts, and returns the previous writer so it can be restored. The writer
+// belongs to the evaluation rather than to a scope: environments enclosed by
+// this one start out with it, and the evaluator passes it on to the
+// environments of the functions called from it, wherever they were defined.
+func (e *Environment) SetOutput(w io.Writer) io.Writer {
+	previous := e.output
+	e.output = w
+	return previous
+}
+
+// Output returns the writer set with SetOutput, nil if there is none.
+func (e *Environment) Output() io.Writer {
+	return e.output
+}
+
+// Tracer observes evaluation. Enter is called before each node is evaluated
+// and Exit once it has been, with its result. Calls nest the same way the
+// nodes do.
+type Tracer interface {
+	Enter(node ast.Node)
+	Exit(node ast.Node, result Object)
+}
+
+// SetTracer installs t to observe evaluation in the environment, nil
+// disables tracing. Like the output, the tracer belongs to the evaluation:
+// enclosed environments and those of called functions are traced too. It
+// returns the previously installed tracer so it can be restored.
+func (e *Environment) SetTracer(t Tracer) Tracer {
+	previous := e.tracer
+	e.tracer = t
+	return previous
+}
+
+// Tracer returns the tracer installed with SetTracer, nil if there is none.
+func (e *Environment) Tracer() Tracer {
+	return e.tracer
+}
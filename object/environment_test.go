@@ -0,0 +1,201 @@
+package object
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	env := NewEnvironment()
+	env.Set("answer", &Integer{Value: 42})
+	env.Freeze()
+
+	if !env.Frozen() {
+		t.Fatalf("env is not frozen after Freeze")
+	}
+
+	// reads still work
+	val, ok := env.Get("answer")
+	if !ok || val.(*Integer).Value != 42 {
+		t.Errorf("answer not readable after Freeze. got=%v", val)
+	}
+
+	// direct mutation is blocked, for new and existing names alike
+	for _, name := range []string{"answer", "other"} {
+		result := env.Set(name, &Integer{Value: 1})
+
+		err, ok := result.(*Error)
+		if !ok {
+			t.Fatalf("Set(%q) on frozen env did not return Error. got=%T", name, result)
+		}
+		expected := "cannot set " + name + ", environment is frozen"
+		if err.Message != expected {
+			t.Errorf("wrong message. got=%q, want=%q", err.Message, expected)
+		}
+	}
+
+	if val, _ := env.Get("answer"); val.(*Integer).Value != 42 {
+		t.Errorf("answer changed in frozen env. got=%d", val.(*Integer).Value)
+	}
+	if _, ok := env.Get("other"); ok {
+		t.Errorf("other was defined in frozen env")
+	}
+
+	// enclosed scopes can still define names, even shadowing frozen ones
+	child := NewEnclosedEnviroment(env)
+	if child.Frozen() {
+		t.Fatalf("child of frozen env is frozen")
+	}
+	if _, ok := child.Set("answer", &Integer{Value: 7}).(*Error); ok {
+		t.Fatalf("Set on child of frozen env failed")
+	}
+	if val, _ := child.Get("answer"); val.(*Integer).Value != 7 {
+		t.Errorf("child does not see its own binding. got=%d", val.(*Integer).Value)
+	}
+	if val, _ := env.Get("answer"); val.(*Integer).Value != 42 {
+		t.Errorf("child binding leaked into frozen env. got=%d", val.(*Integer).Value)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("counter", &Integer{Value: 0})
+	inner := NewEnclosedEnviroment(NewEnclosedEnviroment(outer))
+
+	// the inner scope updates the outer binding rather than shadowing it
+	for i := int64(1); i <= 3; i++ {
+		if !inner.Assign("counter", &Integer{Value: i}) {
+			t.Fatalf("Assign(counter) returned false")
+		}
+	}
+
+	if val, _ := outer.Get("counter"); val.(*Integer).Value != 3 {
+		t.Errorf("outer counter not updated. got=%d", val.(*Integer).Value)
+	}
+	if _, ok := inner.store["counter"]; ok {
+		t.Errorf("Assign created a shadowing binding in the inner scope")
+	}
+
+	// the nearest binding is the one updated
+	inner.Set("counter", &Integer{Value: 10})
+	inner.Assign("counter", &Integer{Value: 11})
+	if val, _ := inner.Get("counter"); val.(*Integer).Value != 11 {
+		t.Errorf("inner counter not updated. got=%d", val.(*Integer).Value)
+	}
+	if val, _ := outer.Get("counter"); val.(*Integer).Value != 3 {
+		t.Errorf("outer counter changed through shadowed name. got=%d", val.(*Integer).Value)
+	}
+
+	// unbound names are not defined
+	if inner.Assign("missing", &Integer{Value: 1}) {
+		t.Errorf("Assign(missing) returned true")
+	}
+	if _, ok := inner.Get("missing"); ok {
+		t.Errorf("Assign defined an unbound name")
+	}
+
+	// frozen scopes are not updated
+	outer.Set("answer", &Integer{Value: 42})
+	outer.Freeze()
+	if inner.Assign("answer", &Integer{Value: 1}) {
+		t.Errorf("Assign to a frozen scope returned true")
+	}
+	if val, _ := outer.Get("answer"); val.(*Integer).Value != 42 {
+		t.Errorf("answer changed in frozen env. got=%d", val.(*Integer).Value)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	inner := NewEnclosedEnviroment(outer)
+	inner.Set("x", &Integer{Value: 2})
+	inner.Set("y", &Integer{Value: 3})
+
+	if !inner.Delete("y") {
+		t.Errorf("Delete(y) returned false")
+	}
+	if _, ok := inner.Get("y"); ok {
+		t.Errorf("y still bound after Delete")
+	}
+	if inner.Delete("y") {
+		t.Errorf("Delete(y) returned true for a deleted name")
+	}
+	if inner.Delete("missing") {
+		t.Errorf("Delete(missing) returned true")
+	}
+
+	// deleting the inner x uncovers the outer one, which is left alone
+	if !inner.Delete("x") {
+		t.Errorf("Delete(x) returned false")
+	}
+	if val, ok := inner.Get("x"); !ok || val.(*Integer).Value != 1 {
+		t.Errorf("outer x not visible after Delete. got=%v", val)
+	}
+	if inner.Delete("x") {
+		t.Errorf("Delete(x) removed the outer binding")
+	}
+	if _, ok := outer.Get("x"); !ok {
+		t.Errorf("outer x was deleted")
+	}
+
+	// frozen scopes keep their bindings
+	outer.Freeze()
+	if outer.Delete("x") {
+		t.Errorf("Delete on a frozen env returned true")
+	}
+	if _, ok := outer.Get("x"); !ok {
+		t.Errorf("x deleted from frozen env")
+	}
+}
+
+func TestNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("map", &Integer{Value: 1})
+	outer.Set("x", &Integer{Value: 1})
+	inner := NewEnclosedEnviroment(outer)
+	inner.Set("x", &Integer{Value: 2})
+	inner.Set("b", &Integer{Value: 3})
+
+	expected := []string{"b", "map", "x"}
+	if names := inner.Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong names. got=%v, want=%v", names, expected)
+	}
+
+	// names of inner scopes are not visible from outer ones
+	expected = []string{"map", "x"}
+	if names := outer.Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong outer names. got=%v, want=%v", names, expected)
+	}
+
+	if names := NewEnvironment().Names(); len(names) != 0 {
+		t.Errorf("empty env has names. got=%v", names)
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	var first, second bytes.Buffer
+
+	outer := NewEnvironment()
+	if outer.Output() != nil {
+		t.Fatalf("new env has an output. got=%v", outer.Output())
+	}
+
+	if previous := outer.SetOutput(&first); previous != nil {
+		t.Errorf("wrong previous output. got=%v", previous)
+	}
+
+	// enclosed environments start out with the output of the outer one
+	inner := NewEnclosedEnviroment(outer)
+	if inner.Output() != &first {
+		t.Errorf("enclosed env did not get the outer output")
+	}
+
+	if previous := inner.SetOutput(&second); previous != &first {
+		t.Errorf("wrong previous output. got=%v", previous)
+	}
+	if outer.Output() != &first {
+		t.Errorf("setting the inner output changed the outer one")
+	}
+}
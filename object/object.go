@@ -3,6 +3,9 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/dominicgaliano/interpreter-demo/ast"
@@ -17,11 +20,17 @@ type Object interface {
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
+	STRING_OBJ       = "STRING"
+	BUILTIN_OBJ      = "BUILTIN"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	EXIT_OBJ         = "EXIT"
 )
 
 type Integer struct {
@@ -33,6 +42,24 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+// Float is a finite float64. Monkey has no NaN or infinities, the evaluator
+// reports operations that would produce them as errors instead.
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Inspect always shows a fractional part or exponent, so 2.0 is not
+// mistaken for the integer 2.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -59,7 +86,16 @@ type Error struct {
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "Error: " + e.Message }
+func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+
+// Exit is produced by the exit builtin. Like an Error it unwinds evaluation
+// up to the program, where it stops and Code is surfaced to the host.
+type Exit struct {
+	Code int64
+}
+
+func (e *Exit) Type() ObjectType { return EXIT_OBJ }
+func (e *Exit) Inspect() string  { return fmt.Sprintf("exit(%d)", e.Code) }
 
 type Function struct {
 	Parameters []*ast.Identifier
@@ -85,3 +121,141 @@ func (f *Function) Inspect() string  {
 
     return out.String()
 }
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+// BuiltinFunction is the signature of functions implemented natively in Go
+// and exposed to Monkey programs. env is the environment of the call, which
+// builtins calling back into Monkey functions pass on so the evaluation
+// keeps its output.
+type BuiltinFunction func(env *Environment, args ...Object) Object
+
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function" }
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashKey is the comparable key used to store an object in a Hash. Objects
+// that are equal by value produce equal hash keys.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by objects that can be used as hash keys.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+
+	if b.Value {
+		value = 1
+	} else {
+		value = 0
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// HashKey combines the hash keys of the elements in order, so arrays with
+// equal elements produce equal keys. It is only meaningful when every
+// element is itself hashable, use HashKeyOf to check.
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+
+	for _, el := range a.Elements {
+		key, _ := HashKeyOf(el)
+		fmt.Fprintf(h, "%s:%d;", key.Type, key.Value)
+	}
+
+	return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
+// HashKeyOf returns the hash key of obj and whether obj is usable as a hash
+// key. Arrays are only usable when all of their elements are.
+func HashKeyOf(obj Object) (HashKey, bool) {
+	hashable, ok := obj.(Hashable)
+	if !ok {
+		return HashKey{}, false
+	}
+
+	if array, ok := obj.(*Array); ok {
+		for _, el := range array.Elements {
+			if _, ok := HashKeyOf(el); !ok {
+				return HashKey{}, false
+			}
+		}
+	}
+
+	return hashable.HashKey(), true
+}
+
+// HashPair keeps the original key object alongside its value so the hash
+// can be inspected and iterated.
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+
+// Inspect renders the pairs sorted, since map iteration order is random.
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+	sort.Strings(pairs)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,52 @@
+package object
+
+import "testing"
+
+func TestStringObject(t *testing.T) {
+	s := &String{Value: "hello"}
+
+	if s.Inspect() != "hello" {
+		t.Errorf("s.Inspect() wrong. got=%q", s.Inspect())
+	}
+	if s.Type() != STRING_OBJ {
+		t.Errorf("s.Type() wrong. got=%q, want=%q", s.Type(), STRING_OBJ)
+	}
+}
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+	if hello1.HashKey() == diff.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestIntegerAndBooleanHashKeys(t *testing.T) {
+	one1 := &Integer{Value: 1}
+	one2 := &Integer{Value: 1}
+	two := &Integer{Value: 2}
+
+	if one1.HashKey() != one2.HashKey() {
+		t.Errorf("integers with same value have different hash keys")
+	}
+	if one1.HashKey() == two.HashKey() {
+		t.Errorf("integers with different values have same hash keys")
+	}
+
+	if (&Boolean{Value: true}).HashKey() != (&Boolean{Value: true}).HashKey() {
+		t.Errorf("equal booleans have different hash keys")
+	}
+	if (&Boolean{Value: true}).HashKey() == (&Boolean{Value: false}).HashKey() {
+		t.Errorf("true and false have same hash keys")
+	}
+
+	// keys of different types never collide, even when the values match
+	if one1.HashKey() == (&Boolean{Value: true}).HashKey() {
+		t.Errorf("1 and true have same hash keys")
+	}
+}
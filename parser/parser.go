@@ -17,16 +17,28 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	PIPE        // |>
+	OR          // ||
+	AND         // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
+	INDEX       // array[index]
 )
 
+// maxNestingDepth bounds how deeply expressions may nest, ex. ((((x)))).
+// Parsing is recursive, and exhausting the goroutine stack is a fatal error
+// that cannot be recovered from.
+const maxNestingDepth = 512
+
 // precedences map operator tokens to their respective precedence levels.
 var precedences = map[token.TokenType]int{
+	token.PIPE:     PIPE,
+	token.OR:       OR,
+	token.AND:      AND,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
@@ -35,7 +47,28 @@ var precedences = map[token.TokenType]int{
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.PERCENT:  PRODUCT,
     token.LPAREN: CALL,
+	token.LBRACKET: INDEX,
+}
+
+// Precedence returns the precedence of tokenType when used as an infix
+// operator, LOWEST if it is not one. Higher binds more tightly.
+func Precedence(tokenType token.TokenType) int {
+	if p, ok := precedences[tokenType]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+// Precedences returns a copy of the precedence of every infix operator,
+// for tools that need the whole table.
+func Precedences() map[token.TokenType]int {
+	table := make(map[token.TokenType]int, len(precedences))
+	for tokenType, p := range precedences {
+		table[tokenType] = p
+	}
+	return table
 }
 
 // prefixParseFn is called when we encounter an associated token type in prefix
@@ -59,15 +92,39 @@ type Parser struct {
 	// maps tokens to appropriate prefix and infix parsers
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	// depth is the number of parseExpression calls currently active
+	depth int
+	// tokensRead counts calls to nextToken, used to check that parsing
+	// makes forward progress
+	tokensRead int
+
+	attachComments bool
+	// comments read from the lexer and not yet attached to a statement
+	comments []token.Token
+}
+
+// Options configures a Parser created with NewWithOptions.
+type Options struct {
+	// AttachComments attaches comments to the statements they belong to, see
+	// ast.Comments. The lexer must be created with KeepComments, otherwise
+	// there are no comments to attach. Without it comment tokens are ignored.
+	AttachComments bool
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	return NewWithOptions(l, Options{})
+}
+
+func NewWithOptions(l *lexer.Lexer, opts Options) *Parser {
+	p := &Parser{l: l, errors: []string{}, attachComments: opts.AttachComments}
 
 	// Register prefix parsing functions
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -75,6 +132,9 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	// Register infix parsing functions
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -82,11 +142,16 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
     p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
 	// Read two tokens, so currToken and peekToken are set
 	p.nextToken()
@@ -96,8 +161,16 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	p.tokensRead++
 	p.currToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	for p.peekTokenIs(token.COMMENT) {
+		if p.attachComments {
+			p.comments = append(p.comments, p.peekToken)
+		}
+		p.peekToken = p.l.NextToken()
+	}
 }
 
 func (p *Parser) currTokenIs(t token.TokenType) bool {
@@ -108,6 +181,14 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 	return p.peekToken.Type == t
 }
 
+func (p *Parser) peekPrecedence() int {
+	return Precedence(p.peekToken.Type)
+}
+
+func (p *Parser) currPrecedence() int {
+	return Precedence(p.currToken.Type)
+}
+
 // expectPeek checks if the next token is of the expected type.
 // If it is, it advances the tokens and returns true.
 // Otherwise, it returns false.
@@ -124,15 +205,21 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// errorAt records a parse error, prefixed with the line:column tok starts at
+// so every error points at the source that caused it.
+func (p *Parser) errorAt(tok token.Token, format string, a ...interface{}) {
+	msg := fmt.Sprintf("%d:%d: ", tok.Line, tok.Column) + fmt.Sprintf(format, a...)
+	p.errors = append(p.errors, msg)
+}
+
+// peekError reports that the next token is not the expected one.
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
 }
 
-func (p *Parser) noPrefixParserFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+func (p *Parser) noPrefixParserFnError(tok token.Token) {
+	p.errorAt(tok, "no prefix parse function for %s found", tok.Type)
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -140,28 +227,116 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !p.currTokenIs(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseCommentedStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
 	}
 
+	// comments after the last statement have nothing following them to lead
+	if len(p.comments) > 0 && len(program.Statements) > 0 {
+		last := commentsOf(program.Statements[len(program.Statements)-1])
+		if last != nil {
+			last.TrailingComments = append(last.TrailingComments, p.takeComments()...)
+		}
+	}
+
 	return program
 }
 
+// parseCommentedStatement parses a statement, attaching the comments before
+// it as leading comments and those up to the end of its last line as
+// trailing comments.
+func (p *Parser) parseCommentedStatement() ast.Statement {
+	if !p.attachComments {
+		return p.parseStatement()
+	}
+
+	start := p.currToken
+	leading := p.takeComments(func(comment token.Token) bool {
+		return comment.Line < start.Line ||
+			comment.Line == start.Line && comment.Column < start.Column
+	})
+
+	stmt := p.parseStatement()
+	if stmt == nil {
+		return nil
+	}
+
+	end := p.currToken
+	trailing := p.takeComments(func(comment token.Token) bool {
+		return comment.Line <= end.Line
+	})
+
+	if comments := commentsOf(stmt); comments != nil {
+		comments.LeadingComments = leading
+		comments.TrailingComments = trailing
+	}
+
+	return stmt
+}
+
+// takeComments removes the pending comments matching every filter, in order,
+// and returns their text.
+func (p *Parser) takeComments(filters ...func(token.Token) bool) []string {
+	var taken []string
+	remaining := p.comments[:0]
+
+	for _, comment := range p.comments {
+		matches := true
+		for _, filter := range filters {
+			matches = matches && filter(comment)
+		}
+
+		if matches {
+			taken = append(taken, comment.Literal)
+		} else {
+			remaining = append(remaining, comment)
+		}
+	}
+
+	p.comments = remaining
+	return taken
+}
+
+// commentsOf returns the comments of a statement that can have them.
+func commentsOf(stmt ast.Statement) *ast.Comments {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return &stmt.Comments
+	case *ast.DestructuringStatement:
+		return &stmt.Comments
+	case *ast.ReturnStatement:
+		return &stmt.Comments
+	case *ast.WhileStatement:
+		return &stmt.Comments
+	case *ast.ExpressionStatement:
+		return &stmt.Comments
+	default:
+		return nil
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
 func (p *Parser) parseLetStatement() ast.Statement {
+	// let [a, b] = ...; and let {x, y} = ...;
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		return p.parseDestructuringStatement()
+	}
+
 	stmt := &ast.LetStatement{Token: p.currToken}
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -183,9 +358,54 @@ func (p *Parser) parseLetStatement() ast.Statement {
 	return stmt
 }
 
+// parseDestructuringStatement parses a let statement binding an array or
+// hash pattern. Starts on the let token.
+func (p *Parser) parseDestructuringStatement() ast.Statement {
+	stmt := &ast.DestructuringStatement{Token: p.currToken}
+
+	p.nextToken()
+	patternToken := p.currToken
+
+	if p.currTokenIs(token.LBRACKET) {
+		names := p.parseIdentifierList(token.RBRACKET)
+		if names == nil {
+			return nil
+		}
+		stmt.Pattern = &ast.ArrayPattern{Token: patternToken, Elements: names}
+	} else {
+		names := p.parseIdentifierList(token.RBRACE)
+		if names == nil {
+			return nil
+		}
+		stmt.Pattern = &ast.HashPattern{Token: patternToken, Keys: names}
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseReturnStatement() ast.Statement {
 	stmt := &ast.ReturnStatement{Token: p.currToken}
 
+	// a bare "return;" has no value, it returns NULL
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) ||
+		p.peekTokenIs(token.EOF) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
 	p.nextToken()
 
     stmt.ReturnValue = p.parseExpression(LOWEST)
@@ -197,6 +417,33 @@ func (p *Parser) parseReturnStatement() ast.Statement {
 	return stmt
 }
 
+// ex. while (i < 10) { let i = i + 1; }
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.currToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // An expression statement is a statement that consists of a single expression.
 // ex. 5 + 5;
 func (p *Parser) parseExpressionStatement() ast.Statement {
@@ -215,9 +462,18 @@ func (p *Parser) parseExpressionStatement() ast.Statement {
 // token. It uses the precedence of the current token to determine which parsing
 // function to call.
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > maxNestingDepth {
+		p.errorAt(p.currToken, "expression nested too deeply, maximum depth is %d",
+			maxNestingDepth)
+		return nil
+	}
+
 	prefix := p.prefixParseFns[p.currToken.Type]
 	if prefix == nil {
-		p.noPrefixParserFnError(p.currToken.Type)
+		p.noPrefixParserFnError(p.currToken)
 		return nil
 	}
 
@@ -248,9 +504,23 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as an integer",
+		p.errorAt(p.currToken, "could not parse %q as an integer",
+			p.currToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.currToken}
+
+	value, err := strconv.ParseFloat(p.currToken.Literal, 64)
+	if err != nil {
+		p.errorAt(p.currToken, "could not parse %q as a float",
 			p.currToken.Literal)
-		p.errors = append(p.errors, msg)
 		return nil
 	}
 
@@ -259,6 +529,10 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.currToken,
@@ -272,24 +546,6 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expression
 }
 
-func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
-	}
-
-	// Fallback
-	return LOWEST
-}
-
-func (p *Parser) currPrecedence() int {
-	if p, ok := precedences[p.currToken.Type]; ok {
-		return p
-	}
-
-	// Fallback
-	return LOWEST
-}
-
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.currToken,
@@ -363,13 +619,18 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.currTokenIs(token.RBRACE) && !p.currTokenIs(token.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseCommentedStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
 
+	// stopping at EOF guarantees termination, but the block is incomplete
+	if p.currTokenIs(token.EOF) {
+		p.errorAt(p.currToken, "unterminated block, expected } before EOF")
+	}
+
 	return block
 }
 
@@ -381,6 +642,9 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	}
 
 	lit.Parameters = p.parseFunctionParameters() // ends on ')'
+	if lit.Parameters == nil {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -392,27 +656,39 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 }
 
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	return p.parseIdentifierList(token.RPAREN)
+}
+
+// parseIdentifierList parses a comma-separated list of identifiers
+// terminated by the end token, as used by function parameters and
+// destructuring patterns. Starts on the opening token and ends on the end
+// token. Returns nil on error.
+func (p *Parser) parseIdentifierList(end token.TokenType) []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
 
-	// no parameters
-	if p.peekTokenIs(token.RPAREN) {
+	// empty list
+	if p.peekTokenIs(end) {
 		p.nextToken()
 		return identifiers
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
 	ident := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		ident := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 		identifiers = append(identifiers, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
 
@@ -427,27 +703,148 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseCallArguments() []ast.Expression {
-    args := []ast.Expression{}
+	return p.parseExpressionList(token.RPAREN)
+}
 
-    if p.peekTokenIs(token.RPAREN) {
-        p.nextToken()
-        return args
-    }
-    
-    p.nextToken()
-    args = append(args, p.parseExpression(LOWEST))
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.currToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
 
-    for p.peekTokenIs(token.COMMA) {
-        p.nextToken()
-        p.nextToken()
-        args = append(args, p.parseExpression(LOWEST))
-    }
+	return array
+}
 
-    if !p.expectPeek(token.RPAREN) {
-        return nil
-    }
+// parseExpressionList parses a comma-separated list of expressions terminated
+// by the end token. It is shared by call arguments and array literals.
+// Starts on the opening token and ends on the end token.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseListElement())
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		list = append(list, p.parseListElement())
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+// parseListElement parses a single element of an expression list, which
+// may be spread with a leading '...'.
+func (p *Parser) parseListElement() ast.Expression {
+	if !p.currTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadElement{Token: p.currToken}
 
-    return args
+	p.nextToken()
+	spread.Right = p.parseExpression(LOWEST)
+
+	return spread
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.currToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseHashLiteral parses comma-separated key: value pairs.
+// Starts on the '{' token and ends on the '}' token.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.currToken}
+	hash.Pairs = make(map[ast.Expression]ast.Expression)
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs[key] = value
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+// parseMatchExpression parses a subject followed by comma-separated
+// `type => expression` arms in braces. Arm bodies are single expressions.
+// Starts on the match token and ends on the '}' token.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.currToken}
+
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	exp.Arms = []*ast.MatchArm{}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		// fn is a keyword, but names the function type here
+		if !p.currTokenIs(token.IDENT) && !p.currTokenIs(token.FUNCTION) {
+			p.errorAt(p.currToken, "expected type name in match arm, got %s",
+				p.currToken.Type)
+			return nil
+		}
+
+		arm := &ast.MatchArm{
+			Pattern: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal},
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+
+		p.nextToken()
+		arm.Body = p.parseExpression(LOWEST)
+		exp.Arms = append(exp.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return exp
 }
 
 // helper functions to register prefix and infix parsing functions associated with tokenType.
@@ -2,10 +2,13 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/dominicgaliano/interpreter-demo/ast"
 	"github.com/dominicgaliano/interpreter-demo/lexer"
+	"github.com/dominicgaliano/interpreter-demo/token"
 )
 
 func TestLetStatements(t *testing.T) {
@@ -42,6 +45,26 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestLetStatementExpressionValue(t *testing.T) {
+	l := lexer.New("let z = x + y;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt := program.Statements[0]
+	if !testLetStatement(t, stmt, "z") {
+		return
+	}
+
+	val := stmt.(*ast.LetStatement).Value
+	testInfixExpression(t, val, "x", "+", "y")
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	// Asserts if the token literal of the interface value 's' is equal
 	// to 'let'.
@@ -124,6 +147,36 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestReturnStatementString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return;", "return;"},
+		{"return", "return;"},
+		{"return 5;", "return 5;"},
+		{"return 5 + 5 * 2;", "return (5 + (5 * 2));"},
+		{"return (5 + 5) * 2", "return ((5 + 5) * 2);"},
+	}
+
+	for _, tt := range tests {
+		program := New(lexer.New(tt.input)).ParseProgram()
+		if program.String() != tt.expected {
+			t.Errorf("wrong String for %q. got=%q, want=%q",
+				tt.input, program.String(), tt.expected)
+		}
+
+		// the String output parses back to the same program
+		p := New(lexer.New(program.String()))
+		reparsed := p.ParseProgram()
+		checkParserErrors(t, p)
+		if reparsed.String() != tt.expected {
+			t.Errorf("String of %q does not round-trip. got=%q, want=%q",
+				tt.input, reparsed.String(), tt.expected)
+		}
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar;"
 
@@ -188,6 +241,65 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "1.5;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program does not have enough statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got=%T",
+			program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 1.5 {
+		t.Fatalf("literal.Value not %g, got=%g", 1.5, literal.Value)
+	}
+	if literal.TokenLiteral() != "1.5" {
+		t.Errorf("literal.TokenLiteral not %s. got=%s", "1.5",
+			literal.TokenLiteral())
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world";`, "hello world"},
+		{`"";`, ""},
+		{`"fn(x) { x }"`, "fn(x) { x }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -292,6 +404,8 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"true && false", true, "&&", false},
+		{"false || true", false, "||", true},
 	}
 
 	for _, tt := range infixTests {
@@ -330,6 +444,479 @@ func TestParsingInfixExpressions(t *testing.T) {
 	}
 }
 
+// TestInfixExpressionTree checks the shape of the tree built for mixed
+// precedences rather than its String output.
+func TestInfixExpressionTree(t *testing.T) {
+	l := lexer.New("1 + 2 * 3; 5 > 4 == 3 < 4;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	// 1 + (2 * 3)
+	sum := program.Statements[0].(*ast.ExpressionStatement).Expression
+	sumExp, ok := sum.(*ast.InfixExpression)
+	if !ok || sumExp.Operator != "+" {
+		t.Fatalf("exp is not a + InfixExpression. got=%T(%s)", sum, sum)
+	}
+	testIntegerLiteral(t, sumExp.Left, 1)
+	testInfixExpression(t, sumExp.Right, 2, "*", 3)
+
+	// (5 > 4) == (3 < 4)
+	eq := program.Statements[1].(*ast.ExpressionStatement).Expression
+	eqExp, ok := eq.(*ast.InfixExpression)
+	if !ok || eqExp.Operator != "==" {
+		t.Fatalf("exp is not a == InfixExpression. got=%T(%s)", eq, eq)
+	}
+	testInfixExpression(t, eqExp.Left, 5, ">", 4)
+	testInfixExpression(t, eqExp.Right, 3, "<", 4)
+}
+
+func TestPrecedenceHelpers(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCurr int
+		expectedPeek int
+	}{
+		{"a + b", LOWEST, SUM},
+		{"+ -", SUM, SUM},
+		{"* /", PRODUCT, PRODUCT},
+		{"== !=", EQUALS, EQUALS},
+		{"< >", LESSGREATER, LESSGREATER},
+		{"f(", LOWEST, CALL},
+		{"a[", LOWEST, INDEX},
+		{"|> 1", PIPE, LOWEST},
+		{"|| &&", OR, AND},
+		{"; }", LOWEST, LOWEST},
+		{"x", LOWEST, LOWEST}, // the peek token is EOF
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+
+		if got := p.currPrecedence(); got != tt.expectedCurr {
+			t.Errorf("wrong currPrecedence for %q on %q. got=%d, want=%d",
+				tt.input, p.currToken.Literal, got, tt.expectedCurr)
+		}
+		if got := p.peekPrecedence(); got != tt.expectedPeek {
+			t.Errorf("wrong peekPrecedence for %q on %q. got=%d, want=%d",
+				tt.input, p.peekToken.Literal, got, tt.expectedPeek)
+		}
+	}
+}
+
+func TestGroupedExpressionTree(t *testing.T) {
+	l := lexer.New("(1 + 2) * 3")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	// unlike 1 + 2 * 3, the sum is the left operand of the product
+	exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+	product, ok := exp.(*ast.InfixExpression)
+	if !ok || product.Operator != "*" {
+		t.Fatalf("exp is not a * InfixExpression. got=%T(%s)", exp, exp)
+	}
+	testInfixExpression(t, product.Left, 1, "+", 2)
+	testIntegerLiteral(t, product.Right, 3)
+}
+
+func TestGroupedExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(1 + 2", "1:7: expected next token to be ), got EOF instead"},
+		{"(1 + 2; 3", "1:7: expected next token to be ), got ; instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. got=%q, want first=%q",
+				tt.input, errors, tt.expected)
+		}
+	}
+}
+
+func TestExportedPrecedences(t *testing.T) {
+	if Precedence(token.ASTERISK) <= Precedence(token.PLUS) {
+		t.Errorf("* does not outrank +")
+	}
+	if Precedence(token.EQ) >= Precedence(token.PLUS) ||
+		Precedence(token.EQ) >= Precedence(token.ASTERISK) {
+		t.Errorf("== is not below + and *")
+	}
+	if Precedence(token.SEMICOLON) != LOWEST {
+		t.Errorf("non-operator does not have LOWEST precedence. got=%d",
+			Precedence(token.SEMICOLON))
+	}
+
+	table := Precedences()
+	if table[token.SLASH] != PRODUCT || len(table) != len(precedences) {
+		t.Errorf("Precedences does not match the parser's table. got=%v", table)
+	}
+
+	// the snapshot is a copy, changing it does not affect parsing
+	table[token.PLUS] = INDEX
+	if Precedence(token.PLUS) != SUM {
+		t.Errorf("modifying Precedences changed the parser's table")
+	}
+}
+
+func TestAttachComments(t *testing.T) {
+	input := `// the answer
+/* to everything */
+let x = 42; // trailing
+x
+// dangling`
+
+	l := lexer.NewWithOptions(input, lexer.Options{KeepComments: true})
+	p := NewWithOptions(l, Options{AttachComments: true})
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	let, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.LetStatement. got=%T",
+			program.Statements[0])
+	}
+	testComments(t, let.LeadingComments, []string{"// the answer", "/* to everything */"})
+	testComments(t, let.TrailingComments, []string{"// trailing"})
+
+	expr, ok := program.Statements[1].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not *ast.ExpressionStatement. got=%T",
+			program.Statements[1])
+	}
+	testComments(t, expr.LeadingComments, nil)
+	testComments(t, expr.TrailingComments, []string{"// dangling"})
+
+	// comments are not attached by default, even when the lexer keeps them
+	l = lexer.NewWithOptions(input, lexer.Options{KeepComments: true})
+	p = New(l)
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "let x = 42;x" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+	let = program.Statements[0].(*ast.LetStatement)
+	testComments(t, let.LeadingComments, nil)
+	testComments(t, let.TrailingComments, nil)
+}
+
+func TestAttachCommentsInBlock(t *testing.T) {
+	input := `fn() {
+  // leading
+  x; // trailing
+}`
+
+	l := lexer.NewWithOptions(input, lexer.Options{KeepComments: true})
+	p := NewWithOptions(l, Options{AttachComments: true})
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	stmt := fn.Body.Statements[0].(*ast.ExpressionStatement)
+	testComments(t, stmt.LeadingComments, []string{"// leading"})
+	testComments(t, stmt.TrailingComments, []string{"// trailing"})
+}
+
+func testComments(t *testing.T, got, want []string) {
+	if len(got) != len(want) {
+		t.Fatalf("wrong number of comments. got=%q, want=%q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("comments[%d] wrong. got=%q, want=%q", i, got[i], want[i])
+		}
+	}
+}
+
+// canonicalGolden is the canonical dump of canonicalInput. Update it by hand
+// when the parser's output is meant to change.
+const canonicalInput = `let add = fn(a, b) { a + b * 2 };
+let [x, y] = [1.5, "two"];
+if (!true) { return; } else { add(x, -y)[0] }
+{"b": 2, "a": [...rest]}
+match x { int => 1, _ => 0 }`
+
+const canonicalGolden = `Program
+  Statements[0]: LetStatement @1:1
+    Name: Identifier add @1:5
+    Value: FunctionLiteral @1:11
+      Parameters[0]: Identifier a @1:14
+      Parameters[1]: Identifier b @1:17
+      Body: BlockStatement @1:20
+        Statements[0]: ExpressionStatement @1:22
+          Expression: InfixExpression + @1:24
+            Left: Identifier a @1:22
+            Right: InfixExpression * @1:28
+              Left: Identifier b @1:26
+              Right: IntegerLiteral 2 @1:30
+  Statements[1]: DestructuringStatement @2:1
+    Pattern: ArrayPattern @2:5
+      Elements[0]: Identifier x @2:6
+      Elements[1]: Identifier y @2:9
+    Value: ArrayLiteral @2:14
+      Elements[0]: FloatLiteral 1.5 @2:15
+      Elements[1]: StringLiteral "two" @2:20
+  Statements[2]: ExpressionStatement @3:1
+    Expression: IfExpression @3:1
+      Condition: PrefixExpression ! @3:5
+        Right: Boolean true @3:6
+      Consequence: BlockStatement @3:12
+        Statements[0]: ReturnStatement @3:14
+          ReturnValue: nil
+      Alternative: BlockStatement @3:29
+        Statements[0]: ExpressionStatement @3:31
+          Expression: IndexExpression @3:41
+            Left: CallExpression @3:34
+              Function: Identifier add @3:31
+              Arguments[0]: Identifier x @3:35
+              Arguments[1]: PrefixExpression - @3:38
+                Right: Identifier y @3:39
+            Index: IntegerLiteral 0 @3:42
+  Statements[3]: ExpressionStatement @4:1
+    Expression: HashLiteral @4:1
+      Pairs[0]:
+        Key: StringLiteral "a" @4:10
+        Value: ArrayLiteral @4:15
+          Elements[0]: SpreadElement @4:16
+            Right: Identifier rest @4:19
+      Pairs[1]:
+        Key: StringLiteral "b" @4:2
+        Value: IntegerLiteral 2 @4:7
+  Statements[4]: ExpressionStatement @5:1
+    Expression: MatchExpression @5:1
+      Subject: Identifier x @5:7
+      Arms[0]: MatchArm
+        Pattern: Identifier int @5:11
+        Body: IntegerLiteral 1 @5:18
+      Arms[1]: MatchArm
+        Pattern: Identifier _ @5:21
+        Body: IntegerLiteral 0 @5:26
+`
+
+func TestCanonicalString(t *testing.T) {
+	// hash literals are maps, dump repeatedly to catch any dependence on
+	// their iteration order
+	for i := 0; i < 10; i++ {
+		p := New(lexer.New(canonicalInput))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := ast.CanonicalString(program); got != canonicalGolden {
+			t.Fatalf("canonical dump differs from golden. got=\n%s", got)
+		}
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingEmptyArrayLiteral(t *testing.T) {
+	l := lexer.New("[]")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+	}
+	if array.String() != "[]" {
+		t.Errorf("array.String() wrong. got=%q", array.String())
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	testInfixExpression(t, indexExp.Index, 1, "+", 1)
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	input := `{"one": 1, 2: 0 + 2, true: 15 / 5}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 3 {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+
+	for key, value := range hash.Pairs {
+		switch key := key.(type) {
+		case *ast.StringLiteral:
+			if key.Value != "one" {
+				t.Errorf("unexpected string key %q", key.Value)
+			}
+			testIntegerLiteral(t, value, 1)
+		case *ast.IntegerLiteral:
+			if key.Value != 2 {
+				t.Errorf("unexpected integer key %d", key.Value)
+			}
+			testInfixExpression(t, value, 0, "+", 2)
+		case *ast.Boolean:
+			if !key.Value {
+				t.Errorf("unexpected boolean key false")
+			}
+			testInfixExpression(t, value, 15, "/", 5)
+		default:
+			t.Errorf("unexpected key type %T", key)
+		}
+	}
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+	l := lexer.New("{}")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 0 {
+		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+}
+
+// positions matches the line:column suffixes in a canonical dump.
+var positions = regexp.MustCompile(` @[0-9]+:[0-9]+`)
+
+func TestProgramStringRoundTrip(t *testing.T) {
+	corpus := []string{
+		"let x = 5; let y = x * 2 + 1; x - y",
+		"3 + 4; -5 * 5",
+		"a; -b; !c",
+		"-(-5); !!true; -1.5 * 2.25",
+		`let s = "hello" + " " + "world"; s == "hello world"`,
+		`""`,
+		"if (x) { 1 }",
+		"if (x < y) { x } else { y }; -1",
+		"if (a) { if (b) { c } else { d } } else { e; f }",
+		"if (x) {} else {}",
+		"fn() {}",
+		"let add = fn(a, b) { return a + b; }; add(1, 2 * 3)",
+		"fn(x) { x }(5)",
+		"let f = fn() { return; }; f()",
+		"let g = fn(x) { let y = x; y; }; g(1)",
+		"[1, 2 * 2, [3], []][0]",
+		"a * [1, 2, 3, 4][b * c] * d",
+		`{"one": 1, 2: "two", true: [3], "nested": {"a": 1}}["one"]`,
+		"{}",
+		"let [a, b] = [1, 2]; let {x, y} = point;",
+		"add(...args, 1, ...[2, 3]); [0, ...rest]",
+		"5 |> double |> add(2)",
+		`match x { int => "integer", string => s, _ => fn(y) { y } }`,
+		"if (true) { 1 } [0]",
+		"let i = 0; while (i < 10) { let i = i + 1; }; i",
+		"while (true) { if (x) { return x; } } while (false) {}",
+		`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(10)`,
+	}
+
+	for _, input := range corpus {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("corpus program %q has parser errors: %v", input, p.Errors())
+		}
+
+		printed := program.String()
+		p = New(lexer.New(printed))
+		reparsed := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("String of %q does not parse. printed=%q, errors=%v",
+				input, printed, p.Errors())
+			continue
+		}
+
+		// positions differ after printing, everything else must match
+		want := positions.ReplaceAllString(ast.CanonicalString(program), "")
+		got := positions.ReplaceAllString(ast.CanonicalString(reparsed), "")
+		if got != want {
+			t.Errorf("round trip of %q changed the program. printed=%q\nwant=\n%s\ngot=\n%s",
+				input, printed, want, got)
+		}
+	}
+}
+
 func TestOperatorPrecedenceParsing(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -369,7 +956,7 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		},
 		{
 			"3 + 4; -5 * 5",
-			"(3 + 4)((-5) * 5)",
+			"(3 + 4);((-5) * 5)",
 		},
 		{
 			"5 > 4 == 3 < 4",
@@ -435,6 +1022,54 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"add(...a, b, ...[c + d])",
+			"add(...a, b, ...[(c + d)])",
+		},
+		{
+			"[1, ...a * 2]",
+			"[1, ...(a * 2)]",
+		},
+		{
+			"a |> f |> g(b)",
+			"((a |> f) |> g(b))",
+		},
+		{
+			"a + b |> f == c",
+			"((a + b) |> (f == c))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && c < d || !e",
+			"(((a == b) && (c < d)) || (!e))",
+		},
+		{
+			"a && b |> f",
+			"((a && b) |> f)",
+		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"f(x)[0]",
+			"(f(x)[0])",
+		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -559,6 +1194,137 @@ func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
 	return true
 }
 
+func TestIfExpression(t *testing.T) {
+	input := `if (x < y) { x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(exp.Consequence.Statements) != 1 {
+		t.Errorf("consequence is not 1 statements. got=%d\n",
+			len(exp.Consequence.Statements))
+	}
+
+	consequence, ok := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+			exp.Consequence.Statements[0])
+	}
+
+	if !testIdentifier(t, consequence.Expression, "x") {
+		return
+	}
+
+	if exp.Alternative != nil {
+		t.Errorf("exp.Alternative was not nil. got=%+v", exp.Alternative)
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { let x = x + 1; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statements. got=%d\n", len(stmt.Body.Statements))
+	}
+
+	if !testLetStatement(t, stmt.Body.Statements[0], "x") {
+		return
+	}
+}
+
+func TestWhileStatementErrors(t *testing.T) {
+	tests := []string{
+		"while x < y { x }",
+		"while (x < y { x }",
+		"while (x < y) x",
+		"while (x) { x",
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q, got none", input)
+		}
+	}
+}
+
+func TestUnterminatedBlock(t *testing.T) {
+	tests := []string{
+		"if (x) { x",
+		"if (x) { x } else { y",
+		"fn(x) { let y = x; y",
+		"fn() {",
+		"if (true) {",
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		// the block stops at EOF instead of waiting for a } forever
+		if p.tokensRead > 2*len(input)+8 {
+			t.Errorf("parser read %d tokens for %q", p.tokensRead, input)
+		}
+
+		// the error points just past the end of the input, at EOF
+		expected := fmt.Sprintf("1:%d: unterminated block, expected } before EOF",
+			len(input)+1)
+		found := false
+		for _, msg := range p.Errors() {
+			if msg == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no unterminated block error for %q. got=%q", input, p.Errors())
+		}
+	}
+}
+
 func TestIfElseExpression(t *testing.T) {
 	input := `if (x < y) { x } else { y }`
 
@@ -786,3 +1552,269 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchExpression(t *testing.T) {
+	input := `match x { int => 1, string => "s", fn => f(x), _ => y }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T",
+			stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Subject, "x") {
+		return
+	}
+
+	expectedArms := []string{"int => 1", `string => "s"`, "fn => f(x)", "_ => y"}
+	if len(exp.Arms) != len(expectedArms) {
+		t.Fatalf("wrong number of arms. want=%d, got=%d",
+			len(expectedArms), len(exp.Arms))
+	}
+
+	for i, arm := range expectedArms {
+		if exp.Arms[i].String() != arm {
+			t.Errorf("arm %d wrong. want=%q, got=%q", i, arm, exp.Arms[i].String())
+		}
+	}
+}
+
+func TestPeekErrorPosition(t *testing.T) {
+	input := `let x = 1;
+let y = 2;
+  let 3 = z;`
+
+	p := New(lexer.New(input))
+	p.ParseProgram()
+
+	expected := "3:7: expected next token to be IDENT, got INT instead"
+	if errors := p.Errors(); len(errors) == 0 || errors[0] != expected {
+		t.Errorf("wrong parser errors. want first=%q, got=%q", expected, errors)
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"match x { 1 => 2 }", "1:11: expected type name in match arm, got INT"},
+		{"match x { int 2 }", "1:15: expected next token to be =>, got INT instead"},
+		{"match x { int => 1 string => 2 }", "1:20: expected next token to be ,, got IDENT instead"},
+		{"match x int => 1", "1:9: expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expectedError {
+			t.Errorf("wrong parser errors for %q. want first=%q, got=%q",
+				tt.input, tt.expectedError, errors)
+		}
+	}
+}
+
+func TestFunctionLiteralShapes(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedParams     int
+		expectedStatements int
+	}{
+		{"fn() {}", 0, 0},
+		{"fn() { 1 }", 0, 1},
+		{"fn(x) { let y = x; y * 2 }", 1, 2},
+		{"fn(a, b, c) { a; b; c; }", 3, 3},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
+				stmt.Expression)
+		}
+
+		if len(function.Parameters) != tt.expectedParams {
+			t.Errorf("wrong parameter count for %q. got=%d, want=%d",
+				tt.input, len(function.Parameters), tt.expectedParams)
+		}
+		if len(function.Body.Statements) != tt.expectedStatements {
+			t.Errorf("wrong body statement count for %q. got=%d, want=%d",
+				tt.input, len(function.Body.Statements), tt.expectedStatements)
+		}
+	}
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"fn(1) {}", "1:4: expected next token to be IDENT, got INT instead"},
+		{"fn(x, 2) {}", "1:7: expected next token to be IDENT, got INT instead"},
+		{"fn(x, ) {}", "1:7: expected next token to be IDENT, got ) instead"},
+		{"fn(x y) {}", "1:6: expected next token to be ), got IDENT instead"},
+		{"fn(x {}", "1:6: expected next token to be ), got { instead"},
+		{"fn(x", "1:5: expected next token to be ), got EOF instead"},
+		{"fn(", "1:4: expected next token to be IDENT, got EOF instead"},
+		{"fn x {}", "1:4: expected next token to be (, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expectedError {
+			t.Errorf("wrong parser errors for %q. want first=%q, got=%q",
+				tt.input, tt.expectedError, errors)
+		}
+	}
+}
+
+func TestNestingDepthLimit(t *testing.T) {
+	input := strings.Repeat("(", 100000) + "1" + strings.Repeat(")", 100000)
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	expected := fmt.Sprintf("1:%d: expression nested too deeply, maximum depth is %d",
+		maxNestingDepth+1, maxNestingDepth)
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != expected {
+		t.Fatalf("wrong first parser error. want=%q, got=%q", expected, errors)
+	}
+
+	// nesting up to the limit is still fine
+	input = strings.Repeat("-", maxNestingDepth-1) + "1"
+
+	l = lexer.New(input)
+	p = New(l)
+	p.ParseProgram()
+	checkParserErrors(t, p)
+}
+
+// FuzzParseProgram checks that parsing arbitrary input never panics and
+// always makes forward progress: nextToken is called a bounded number of
+// times relative to the input, so malformed input cannot loop at EOF.
+func FuzzParseProgram(f *testing.F) {
+	seeds := []string{
+		"let",
+		"let x",
+		"let x =",
+		"let = 5;",
+		"return",
+		"return;",
+		"if",
+		"if (",
+		"if (x) {",
+		"if (x) { y } else",
+		"fn",
+		"fn(",
+		"fn(x,",
+		"fn(x) {",
+		"add(1, 2",
+		"[1, 2",
+		"{1: 2",
+		"{1 2}",
+		"a[",
+		"((((((((((",
+		"match",
+		"match x { int =>",
+		"a |>",
+		"}}}}",
+		"let add = fn(x, y) { x + y; }; add(1, 2);",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		// the lexer yields at most one token per byte plus EOF, leave some
+		// headroom for constructs that look past EOF before giving up
+		if limit := 2*len(input) + 8; p.tokensRead > limit {
+			t.Fatalf("parser read %d tokens for %d bytes of input %q",
+				p.tokensRead, len(input), input)
+		}
+
+		// a program parsed without errors must be complete enough to print
+		if len(p.Errors()) == 0 {
+			_ = program.String()
+		}
+	})
+}
+
+func TestDestructuringStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedNames []string
+		expected      string
+	}{
+		{"let [a, b] = [1, 2];", []string{"a", "b"}, "let [a, b] = [1, 2];"},
+		{"let [] = x", []string{}, "let [] = x;"},
+		{"let {x, y} = point;", []string{"x", "y"}, "let {x, y} = point;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.DestructuringStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.DestructuringStatement. got=%T",
+				program.Statements[0])
+		}
+
+		var names []*ast.Identifier
+		switch pattern := stmt.Pattern.(type) {
+		case *ast.ArrayPattern:
+			names = pattern.Elements
+		case *ast.HashPattern:
+			names = pattern.Keys
+		}
+
+		if len(names) != len(tt.expectedNames) {
+			t.Fatalf("wrong number of names. want=%d, got=%d",
+				len(tt.expectedNames), len(names))
+		}
+		for i, name := range tt.expectedNames {
+			testIdentifier(t, names[i], name)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
@@ -4,41 +4,432 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 
+	"github.com/dominicgaliano/interpreter-demo/ast"
 	"github.com/dominicgaliano/interpreter-demo/evaluator"
 	"github.com/dominicgaliano/interpreter-demo/lexer"
 	"github.com/dominicgaliano/interpreter-demo/object"
 	"github.com/dominicgaliano/interpreter-demo/parser"
+	"github.com/dominicgaliano/interpreter-demo/token"
+	"github.com/dominicgaliano/interpreter-demo/version"
 )
 
 const PROMPT = ">> "
 
+// GOODBYE is printed when the input is exhausted, ex. on Ctrl-D.
+const GOODBYE = "Goodbye!"
+
+// Banner returns the welcome message printed when an interactive session
+// starts for the user with the given name.
+func Banner(username string) string {
+	return fmt.Sprintf("Welcome %s, this is the Monkey programming language (v%s)!\n"+
+		"Input commands below:\n", username, version.String())
+}
+
+// Options configures an interactive session started with StartWithOptions.
+type Options struct {
+	// Prompt is printed before reading each line. Defaults to PROMPT.
+	Prompt string
+	// Banner is printed once before the first prompt. Empty prints nothing.
+	Banner string
+	// Env is the environment lines are evaluated in, letting embedders
+	// pre-define values. :replay starts over in a new environment enclosed
+	// by it. Defaults to a new environment enclosed by the prelude.
+	Env *object.Environment
+	// NoPrelude leaves the prelude out of the default environment.
+	NoPrelude bool
+	// MaxLineLength is the length in bytes of the longest line evaluated,
+	// longer lines are skipped with an error. Defaults to 16MB.
+	MaxLineLength int
+}
+
+// defaultMaxLineLength is the default limit on the length of an input line,
+// large enough that long pasted programs are read whole.
+const defaultMaxLineLength = 16 << 20
+
+// Start runs a session with the default prompt, no banner and the default
+// environment.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-    env := object.NewEnvironment()
+	StartWithOptions(in, out, Options{})
+}
+
+func StartWithOptions(in io.Reader, out io.Writer, opts Options) {
+	if opts.Prompt == "" {
+		opts.Prompt = PROMPT
+	}
+	if opts.MaxLineLength <= 0 {
+		opts.MaxLineLength = defaultMaxLineLength
+	}
+	s := &session{env: opts.Env, base: opts.Env, noPrelude: opts.NoPrelude}
+	if s.env == nil {
+		s.env = s.newEnvironment()
+	}
+
+	reader := bufio.NewReader(in)
+
+	io.WriteString(out, opts.Banner)
 
 	for {
-		fmt.Print(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		io.WriteString(out, opts.Prompt)
+		line, tooLong, err := readLine(reader, opts.MaxLineLength)
+		if err != nil {
+			// finish the dangling prompt line so the terminal is left clean
+			io.WriteString(out, "\n")
+			io.WriteString(out, GOODBYE+"\n")
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		if tooLong {
+			fmt.Fprintf(out, "ERROR: line exceeds the maximum length of %d bytes\n",
+				opts.MaxLineLength)
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect() + "\n")
+		// lines starting with ':' are REPL commands, not Monkey source
+		if strings.HasPrefix(line, ":") {
+			runCommand(out, line, s)
+		} else {
+			// REPL-only sugar: "1 + 1, 2 + 2" evaluates and echoes each part
+			for _, source := range splitSequence(line) {
+				if !s.eval(out, source) {
+					break
+				}
+			}
+		}
+
+		if s.exited {
+			io.WriteString(out, GOODBYE+"\n")
+			return
+		}
+	}
+}
+
+// readLine reads the next line from r without its line ending. A line longer
+// than limit bytes is read to its end and discarded, reporting tooLong, so the
+// line after it can still be read. err is only set once no more lines can be
+// read, ex. io.EOF.
+func readLine(r *bufio.Reader, limit int) (line string, tooLong bool, err error) {
+	var buf []byte
+	for started := false; ; started = true {
+		chunk, isPrefix, err := r.ReadLine()
+		if err == io.EOF && started {
+			// the last line filled the buffer exactly and has no line ending
+			return string(buf), tooLong, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+
+		if !tooLong && len(buf)+len(chunk) > limit {
+			tooLong = true
+			buf = nil
+		}
+		if !tooLong {
+			buf = append(buf, chunk...)
+		}
+
+		if !isPrefix {
+			return string(buf), tooLong, nil
+		}
+	}
+}
+
+// session is the state of a running REPL.
+type session struct {
+	env *object.Environment
+	// base is the environment given in Options.Env, if any, which new
+	// environments for the session are enclosed by
+	base *object.Environment
+
+	// history holds the source of every line evaluated without errors, in
+	// order, so it can be saved and replayed
+	history []string
+
+	// exited is set once the exit builtin has been called
+	exited bool
+
+	noPrelude bool
+}
+
+// newEnvironment returns an empty environment for the session, enclosed by
+// the embedder's environment if there is one, otherwise by the prelude
+// unless it is disabled.
+func (s *session) newEnvironment() *object.Environment {
+	if s.base != nil {
+		return object.NewEnclosedEnviroment(s.base)
+	}
+	if s.noPrelude {
+		return object.NewEnvironment()
+	}
+	return object.NewEnclosedEnviroment(evaluator.Prelude())
+}
+
+// eval evaluates source in the session's environment like evalSource,
+// recording it in the history if it succeeds.
+func (s *session) eval(out io.Writer, source string) bool {
+	evaluated, ok := evalSource(out, source, s.env)
+	if !ok {
+		return false
+	}
+
+	if evaluated != nil && evaluated.Type() == object.EXIT_OBJ {
+		s.exited = true
+		return false
+	}
+
+	s.history = append(s.history, strings.TrimSpace(source))
+	return true
+}
+
+// save writes the session's history to path, one line of source per line.
+func (s *session) save(path string) error {
+	contents := ""
+	for _, source := range s.history {
+		contents += source + "\n"
+	}
+
+	return os.WriteFile(path, []byte(contents), 0644)
+}
+
+// replay replaces the session's environment and history with fresh ones,
+// then evaluates each line of the file at path in turn.
+func (s *session) replay(out io.Writer, path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	s.env = s.newEnvironment()
+	s.history = nil
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		if s.exited {
+			break
+		}
+		if strings.TrimSpace(line) != "" {
+			s.eval(out, line)
+		}
+	}
+
+	return nil
+}
+
+// evalSource parses and evaluates source in env, printing parser errors,
+// runtime errors and the echoed result to out. It returns the result and
+// whether source was evaluated without errors.
+func evalSource(
+	out io.Writer,
+	source string,
+	env *object.Environment,
+) (object.Object, bool) {
+	program, evaluated, ok := parseAndEval(out, source, env)
+	if !ok || evaluated == nil {
+		return evaluated, ok
+	}
+
+	// the session ends, there is nothing to echo
+	if evaluated.Type() == object.EXIT_OBJ {
+		return evaluated, true
+	}
+
+	if echoesResult(program) {
+		io.WriteString(out, evaluated.Inspect()+"\n")
+	}
+
+	return evaluated, true
+}
+
+// parseAndEval parses and evaluates source in env, printing parser and
+// runtime errors to out. It returns the program, its result and whether
+// it was evaluated without errors.
+func parseAndEval(
+	out io.Writer,
+	source string,
+	env *object.Environment,
+) (*ast.Program, object.Object, bool) {
+	l := lexer.New(source)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(out, p.Errors())
+		return nil, nil, false
+	}
+
+	defer env.SetOutput(env.SetOutput(out))
+
+	// a runtime error only aborts the current line, bindings made by
+	// earlier lines remain in env
+	evaluated := evaluator.Eval(program, env)
+	if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+		io.WriteString(out, evaluated.Inspect()+"\n")
+		return program, evaluated, false
+	}
+
+	return program, evaluated, true
+}
+
+// splitSequence splits a line on the commas that separate top-level
+// expressions, ex. "1 + 1, add(2, 2)" => "1 + 1", " add(2, 2)". Commas nested
+// in parentheses, brackets, braces, string literals or comments are kept, so
+// call arguments and array elements are unaffected. Blank parts are dropped.
+func splitSequence(line string) []string {
+	parts := []string{}
+	depth, start, inString := 0, 0, false
+
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+
+		switch {
+		case inString:
+			if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '/' && strings.HasPrefix(line[i+1:], "/"):
+			// a line comment runs to the end of the line
+			i = len(line)
+		case ch == '/' && strings.HasPrefix(line[i+1:], "*"):
+			// continue after the closing */, an unterminated block comment
+			// runs to the end of the line
+			if end := strings.Index(line[i+2:], "*/"); end >= 0 {
+				i += 2 + end + 1
+			} else {
+				i = len(line)
+			}
+		case ch == '(' || ch == '[' || ch == '{':
+			depth++
+		case ch == ')' || ch == ']' || ch == '}':
+			depth--
+		case ch == ',' && depth == 0:
+			parts = append(parts, line[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, line[start:])
+
+	nonBlank := []string{}
+	for _, part := range parts {
+		if strings.TrimSpace(part) != "" {
+			nonBlank = append(nonBlank, part)
 		}
 	}
+
+	return nonBlank
+}
+
+// echoesResult reports whether the result of program should be printed.
+// Only lines ending in a bare expression echo their value, statements such
+// as let are silent.
+func echoesResult(program *ast.Program) bool {
+	if len(program.Statements) == 0 {
+		return false
+	}
+
+	last := program.Statements[len(program.Statements)-1]
+	_, ok := last.(*ast.ExpressionStatement)
+	return ok
+}
+
+// runCommand executes a REPL command line such as ":version" in session s.
+func runCommand(out io.Writer, line string, s *session) {
+	name, arg, _ := strings.Cut(line, " ")
+
+	switch name {
+	case ":version":
+		io.WriteString(out, version.String()+"\n")
+	case ":profile":
+		profileSource(out, arg, s)
+	case ":clear":
+		// the prompt is redrawn by the loop as after any other line
+		if isTerminal(out) {
+			io.WriteString(out, clearScreen)
+		}
+	case ":type":
+		// like the type builtin, the value itself is not printed
+		_, evaluated, ok := parseAndEval(out, arg, s.env)
+		if ok && evaluated != nil {
+			io.WriteString(out, evaluator.TypeName(evaluated)+"\n")
+		}
+	case ":tokens":
+		printTokens(out, arg)
+	case ":ast":
+		printAST(out, arg)
+	case ":save":
+		if err := s.save(arg); err != nil {
+			io.WriteString(out, "could not save session: "+err.Error()+"\n")
+		}
+	case ":replay":
+		if err := s.replay(out, arg); err != nil {
+			io.WriteString(out, "could not replay session: "+err.Error()+"\n")
+		}
+	default:
+		io.WriteString(out, "unknown command: "+name+"\n")
+	}
+}
+
+// printTokens prints the tokens source is lexed into, one per line with its
+// type and literal, up to but not including EOF. Comments are included.
+func printTokens(out io.Writer, source string) {
+	l := lexer.NewWithOptions(source, lexer.Options{KeepComments: true})
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(out, "%s %q\n", tok.Type, tok.Literal)
+	}
+}
+
+// printAST prints how source is parsed, with every infix and prefix
+// expression parenthesized to show the grouping, or the parser errors if it
+// cannot be. Nothing is evaluated.
+func printAST(out io.Writer, source string) {
+	p := parser.New(lexer.New(source))
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(out, p.Errors())
+		return
+	}
+
+	if len(program.Statements) != 0 {
+		io.WriteString(out, program.String()+"\n")
+	}
+}
+
+// profileSource evaluates source like any other line, then prints how many
+// times each type of node was evaluated and the time spent on them.
+func profileSource(out io.Writer, source string, s *session) {
+	profiler := evaluator.NewProfiler()
+	previous := s.env.SetTracer(profiler)
+	ok := s.eval(out, source)
+	s.env.SetTracer(previous)
+
+	if ok {
+		io.WriteString(out, profiler.Report())
+	}
+}
+
+// clearScreen is the ANSI sequence moving the cursor home and clearing the
+// screen.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// isTerminal reports whether out is a terminal, so escape sequences such as
+// clearScreen can be written to it. Anything other than a character device,
+// ex. a pipe, file or buffer, is not.
+func isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
 func printParserErrors(out io.Writer, errors []string) {
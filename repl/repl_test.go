@@ -0,0 +1,380 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dominicgaliano/interpreter-demo/object"
+	"github.com/dominicgaliano/interpreter-demo/version"
+)
+
+func TestBanner(t *testing.T) {
+	banner := Banner("monkey")
+
+	if !strings.Contains(banner, "monkey") {
+		t.Errorf("banner does not contain username. got=%q", banner)
+	}
+
+	if !strings.Contains(banner, version.Version) {
+		t.Errorf("banner does not contain version %q. got=%q",
+			version.Version, banner)
+	}
+}
+
+func TestVersionCommand(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(":version\n:nope\n"), &out)
+
+	expected := PROMPT + version.String() + "\n" +
+		PROMPT + "unknown command: :nope\n" +
+		PROMPT + "\n" + GOODBYE + "\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+}
+
+func TestStartWithOptions(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("answer", &object.Integer{Value: 42})
+
+	var out bytes.Buffer
+	StartWithOptions(strings.NewReader("answer\n"), &out, Options{
+		Prompt: "monkey> ",
+		Banner: "hello!\n",
+		Env:    env,
+	})
+
+	expected := "hello!\nmonkey> 42\nmonkey> \nGoodbye!\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+}
+
+func TestRuntimeErrorKeepsState(t *testing.T) {
+	input := "let x = 5;\nx + true\nx * 2\n"
+
+	var out bytes.Buffer
+	StartWithOptions(strings.NewReader(input), &out, Options{Prompt: "> "})
+
+	expected := "> > ERROR: type mismatch: INTEGER + BOOLEAN\n> 10\n> \nGoodbye!\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+}
+
+func TestEchoesOnlyExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;\nx + 1\n", "6\n"},
+		{"let x = 5; x\n", "5\n"},
+		{"5; let x = 6;\n", ""},
+		{"let f = fn() { 1 };\nf()\n", "1\n"},
+		{"let x = -true;\n", "ERROR: unknown operator: -BOOLEAN\n"},
+	}
+
+	for _, tt := range tests {
+		got := runSession(tt.input)
+		if got != tt.expected {
+			t.Errorf("wrong output for %q. got=%q, want=%q",
+				tt.input, got, tt.expected)
+		}
+	}
+}
+
+// testPrompt is distinctive enough to be stripped from session output.
+const testPrompt = "<test-prompt>"
+
+// runSession feeds input to a fresh session and returns everything written
+// to the output with the prompts and closing goodbye removed.
+func runSession(input string) string {
+	var out bytes.Buffer
+	StartWithOptions(strings.NewReader(input), &out, Options{Prompt: testPrompt})
+
+	output := strings.ReplaceAll(out.String(), testPrompt, "")
+	return strings.TrimSuffix(output, "\n"+GOODBYE+"\n")
+}
+
+func TestGoodbyeOnEOF(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(""), &out)
+
+	expected := PROMPT + "\nGoodbye!\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+}
+
+func TestCommaSequence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1+1, 2+2, 3+3\n", "2\n4\n6\n"},
+		{"let x = 2, x * 3\n", "6\n"},
+		{"let add = fn(a, b) { a + b }, add(1, 2), [1, 2]\n", "3\n[1, 2]\n"},
+		{`reverse("a,b"), 1` + "\n", "b,a\n1\n"},
+		{"1, -true, 3\n", "1\nERROR: unknown operator: -BOOLEAN\n"},
+		{"1,,2,\n", "1\n2\n"},
+		// commas in comments do not separate expressions
+		{"let x = 1; // set x, then\nx\n", "1\n"},
+		{"1 /* a, b */\n", "1\n"},
+		{"1 /* a, b */, 2 // c, d\n", "1\n2\n"},
+		{"/**/ 1, 2 /*/, 3\n", "1\n parser errors:\n\t1:4: no prefix parse function for ILLEGAL found\n"},
+	}
+
+	for _, tt := range tests {
+		got := runSession(tt.input)
+		if got != tt.expected {
+			t.Errorf("wrong output for %q. got=%q, want=%q",
+				tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestProfileCommand(t *testing.T) {
+	got := runSession("let double = fn(x) { x * 2 };\n:profile double(2)\ndouble\n")
+
+	lines := strings.Split(got, "\n")
+	if lines[0] != "4" {
+		t.Fatalf("profiled source was not echoed. got=%q", got)
+	}
+	if !strings.HasPrefix(lines[1], "NODE") {
+		t.Fatalf("report header missing. got=%q", got)
+	}
+	if !strings.Contains(got, "\nInfixExpression ") {
+		t.Errorf("report missing InfixExpression. got=%q", got)
+	}
+
+	// later lines are evaluated normally, without a report
+	if strings.Count(got, "NODE") != 1 {
+		t.Errorf("report printed more than once. got=%q", got)
+	}
+	if !strings.HasSuffix(got, "\nfn(x) {\n(x * 2)\n}\n") {
+		t.Errorf("later lines evaluated wrongly. got=%q", got)
+	}
+}
+
+func TestSaveAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+
+	got := runSession("let x = 5;\nlet y = x + true;\nlet y = x * 2, y\n:save " + path + "\n")
+	if got != "ERROR: type mismatch: INTEGER + BOOLEAN\n10\n" {
+		t.Fatalf("wrong output. got=%q", got)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("session was not saved: %s", err)
+	}
+
+	// the line that errored is left out
+	expected := "let x = 5;\nlet y = x * 2\ny\n"
+	if string(saved) != expected {
+		t.Fatalf("wrong session saved. got=%q, want=%q", saved, expected)
+	}
+
+	// a new session has neither binding until the saved one is replayed
+	got = runSession("x\n:replay " + path + "\nx + y\n")
+	expected = "ERROR: identifier not found: x\n10\n15\n"
+	if got != expected {
+		t.Errorf("wrong output. got=%q, want=%q", got, expected)
+	}
+}
+
+func TestReplayKeepsOptionsEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+	if err := os.WriteFile(path, []byte("let x = answer + 1;\nx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := object.NewEnvironment()
+	env.Set("answer", &object.Integer{Value: 42})
+
+	var out bytes.Buffer
+	StartWithOptions(strings.NewReader("let y = 1;\n:replay "+path+"\nanswer + x\n"), &out, Options{
+		Prompt: "> ",
+		Env:    env,
+	})
+
+	expected := "> > 43\n> 85\n> \nGoodbye!\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+
+	// the replay ran in a new environment enclosed by env, not in env itself
+	if _, ok := env.Get("x"); ok {
+		t.Errorf("replayed binding was made in the embedder's environment")
+	}
+}
+
+func TestReplayMissingFile(t *testing.T) {
+	got := runSession(":replay " + filepath.Join(t.TempDir(), "missing") + "\n")
+
+	if !strings.HasPrefix(got, "could not replay session: ") {
+		t.Errorf("wrong output. got=%q", got)
+	}
+}
+
+func TestExitEndsSession(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("1\nexit(), 2\n3\n"), &out)
+
+	expected := PROMPT + "1\n" + PROMPT + GOODBYE + "\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+}
+
+func TestEnvironmentPersistsAcrossLines(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;\nx + 1;\n", "6\n"},
+		{"let double = fn(n) { n * 2 };\nlet y = double(4);\ndouble(y)\n", "16\n"},
+		{"let x = 1;\nlet x = x + 1;\nx\n", "2\n"},
+	}
+
+	for _, tt := range tests {
+		got := runSession(tt.input)
+		if got != tt.expected {
+			t.Errorf("wrong output for %q. got=%q, want=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestPrelude(t *testing.T) {
+	got := runSession("map([1, 2], fn(x) { x * 2 })\n")
+	if got != "[2, 4]\n" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+
+	var out bytes.Buffer
+	StartWithOptions(strings.NewReader("map\n"), &out, Options{Prompt: "> ", NoPrelude: true})
+
+	expected := "> ERROR: identifier not found: map\n> \nGoodbye!\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+}
+
+func TestTypeCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":type 1 + 1\n", "INTEGER\n"},
+		{":type \"hi\"\n", "STRING\n"},
+		{":type fn(x) { x }\n", "FUNCTION\n"},
+		{"let xs = [1];\n:type xs\n", "ARRAY\n"},
+		{":type 1 + true\n", "ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+		{":type let\n", " parser errors:\n\t1:4: expected next token to be IDENT, got EOF instead\n"},
+	}
+
+	for _, tt := range tests {
+		got := runSession(tt.input)
+		if got != tt.expected {
+			t.Errorf("wrong output for %q. got=%q, want=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestTokensCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":tokens 5 + 5\n", "INT \"5\"\n+ \"+\"\nINT \"5\"\n"},
+		{":tokens let x = \"a b\";\n",
+			"LET \"let\"\nIDENT \"x\"\n= \"=\"\nSTRING \"a b\"\n; \";\"\n"},
+		{":tokens 1 # 2 // two\n", "INT \"1\"\nILLEGAL \"#\"\nINT \"2\"\nCOMMENT \"// two\"\n"},
+		{":tokens\n", ""},
+		// nothing is evaluated
+		{":tokens let x = 1;\nx\n", "LET \"let\"\nIDENT \"x\"\n= \"=\"\nINT \"1\"\n; \";\"\n" +
+			"ERROR: identifier not found: x\n"},
+	}
+
+	for _, tt := range tests {
+		got := runSession(tt.input)
+		if got != tt.expected {
+			t.Errorf("wrong output for %q. got=%q, want=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestASTCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":ast 1 + 2 * 3\n", "(1 + (2 * 3))\n"},
+		{":ast -a * b == c && d\n", "((((-a) * b) == c) && d)\n"},
+		{":ast let x = 5; x |> f\n", "let x = 5;(x |> f)\n"},
+		{":ast {\"b\": 2, \"a\": 1, \"c\": 3}\n", "{\"a\": 1, \"b\": 2, \"c\": 3}\n"},
+		{":ast\n", ""},
+		{":ast let\n", " parser errors:\n\t1:4: expected next token to be IDENT, got EOF instead\n"},
+		// nothing is evaluated
+		{":ast let x = 1;\nx\n", "let x = 1;\nERROR: identifier not found: x\n"},
+	}
+
+	for _, tt := range tests {
+		got := runSession(tt.input)
+		if got != tt.expected {
+			t.Errorf("wrong output for %q. got=%q, want=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestClearCommand(t *testing.T) {
+	// output that is not a terminal gets no escape sequences, the session
+	// carries on with its bindings intact
+	got := runSession("let x = 1;\n:clear\nx\n")
+	if got != "1\n" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if isTerminal(file) {
+		t.Errorf("regular file reported as a terminal")
+	}
+	if isTerminal(&bytes.Buffer{}) {
+		t.Errorf("buffer reported as a terminal")
+	}
+}
+
+func TestPutsWritesToSessionOutput(t *testing.T) {
+	got := runSession("puts(\"hi\", 2)\n")
+	if got != "hi\n2\nnull\n" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	// longer than bufio.Reader's default buffer of 4KB
+	long := `len("` + strings.Repeat("a", 100000) + `")`
+	if got := runSession(long + "\n"); got != "100000\n" {
+		t.Errorf("long line not read whole. got=%q", got)
+	}
+
+	var out bytes.Buffer
+	StartWithOptions(strings.NewReader(long+"\n1\n"), &out,
+		Options{Prompt: testPrompt, MaxLineLength: 1000})
+
+	// the long line is reported and skipped, the session carries on
+	expected := testPrompt + "ERROR: line exceeds the maximum length of 1000 bytes\n" +
+		testPrompt + "1\n" + testPrompt + "\n" + GOODBYE + "\n"
+	if out.String() != expected {
+		t.Errorf("wrong output for line over the limit. got=%q, want=%q",
+			out.String(), expected)
+	}
+}
@@ -0,0 +1,172 @@
+// Package runner evaluates complete Monkey programs non-interactively. It
+// backs the file and --eval modes of the command line and can be used by
+// embedders that want the same behavior.
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dominicgaliano/interpreter-demo/evaluator"
+	"github.com/dominicgaliano/interpreter-demo/lexer"
+	"github.com/dominicgaliano/interpreter-demo/object"
+	"github.com/dominicgaliano/interpreter-demo/parser"
+)
+
+// Exit statuses returned to the host process.
+const (
+	StatusOK    = 0
+	StatusError = 1
+)
+
+// Options configures RunWithOptions, EvalStringWithOptions and
+// RunFileWithOptions.
+type Options struct {
+	// NoPrelude evaluates programs in an empty environment, instead of one
+	// enclosed by the prelude that makes map, filter, etc. available. Unused
+	// by RunWithOptions, which is given its environment.
+	NoPrelude bool
+	// MaxSourceSize is the length in bytes of the largest program accepted,
+	// so an embedder is not made to lex and parse arbitrarily large input.
+	// Defaults to 16MB, a negative size disables the limit.
+	MaxSourceSize int
+}
+
+// defaultMaxSourceSize is the limit on the size of a program when
+// Options.MaxSourceSize is zero.
+const defaultMaxSourceSize = 16 << 20
+
+// maxSourceSize returns the limit opts puts on the size of a program, zero if
+// there is none.
+func (opts Options) maxSourceSize() int {
+	switch {
+	case opts.MaxSourceSize == 0:
+		return defaultMaxSourceSize
+	case opts.MaxSourceSize < 0:
+		return 0
+	}
+	return opts.MaxSourceSize
+}
+
+// newEnvironment returns the environment EvalString and RunFile evaluate
+// programs in.
+func newEnvironment(opts Options) *object.Environment {
+	if opts.NoPrelude {
+		return object.NewEnvironment()
+	}
+	return object.NewEnclosedEnviroment(evaluator.Prelude())
+}
+
+// Run parses and evaluates input in env. Parser and runtime errors, and
+// anything the program prints, are written to out. It returns the result of
+// the program, nil if it could not be parsed, along with the exit status the
+// host should use. A program that calls exit stops there, returning the
+// *object.Exit and its code.
+func Run(input string, env *object.Environment, out io.Writer) (object.Object, int) {
+	return RunWithOptions(input, env, out, Options{})
+}
+
+// RunWithOptions is Run configured by opts.
+func RunWithOptions(
+	input string,
+	env *object.Environment,
+	out io.Writer,
+	opts Options,
+) (object.Object, int) {
+	defer env.SetOutput(env.SetOutput(out))
+
+	if limit := opts.maxSourceSize(); limit > 0 && len(input) > limit {
+		tooLarge := &object.Error{Message: fmt.Sprintf(
+			"source exceeds the maximum size of %d bytes", limit)}
+		io.WriteString(out, tooLarge.Inspect()+"\n")
+		return tooLarge, StatusError
+	}
+
+	l := lexer.New(input)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		io.WriteString(out, "parser errors:\n")
+		for _, msg := range p.Errors() {
+			io.WriteString(out, "\t"+msg+"\n")
+		}
+		return nil, StatusError
+	}
+
+	evaluated := evaluator.Eval(program, env)
+	if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+		io.WriteString(out, evaluated.Inspect()+"\n")
+		return evaluated, StatusError
+	}
+
+	if exit, ok := evaluated.(*object.Exit); ok {
+		return exit, int(exit.Code)
+	}
+
+	return evaluated, StatusOK
+}
+
+// EvalString evaluates source in a new environment and, unlike Run, also
+// prints the result to out. Used for one-liners passed with -e.
+func EvalString(source string, out io.Writer) int {
+	return EvalStringWithOptions(source, out, Options{})
+}
+
+// EvalStringWithOptions is EvalString configured by opts.
+func EvalStringWithOptions(source string, out io.Writer, opts Options) int {
+	evaluated, status := RunWithOptions(source, newEnvironment(opts), out, opts)
+	if status == StatusOK && evaluated != nil && evaluated.Type() != object.EXIT_OBJ {
+		io.WriteString(out, evaluated.Inspect()+"\n")
+	}
+
+	return status
+}
+
+// RunFile evaluates the program stored at path in a new environment.
+// Only errors are printed, scripts produce output explicitly.
+func RunFile(path string, out io.Writer) int {
+	return RunFileWithOptions(path, out, Options{})
+}
+
+// RunFileWithOptions is RunFile configured by opts.
+func RunFileWithOptions(path string, out io.Writer, opts Options) int {
+	file, err := os.Open(path)
+	if err != nil {
+		io.WriteString(out, err.Error()+"\n")
+		return StatusError
+	}
+	defer file.Close()
+
+	// reading one byte past the limit is enough for RunWithOptions to reject
+	// the file
+	var reader io.Reader = file
+	if limit := opts.maxSourceSize(); limit > 0 {
+		reader = io.LimitReader(file, int64(limit)+1)
+	}
+
+	source, err := io.ReadAll(reader)
+	if err != nil {
+		io.WriteString(out, err.Error()+"\n")
+		return StatusError
+	}
+
+	_, status := RunWithOptions(stripShebang(string(source)), newEnvironment(opts), out, opts)
+	return status
+}
+
+// stripShebang removes a "#!" interpreter line from the very start of a
+// script, so it can be made executable. The newline is kept so the lines
+// after it keep their numbers.
+func stripShebang(source string) string {
+	if !strings.HasPrefix(source, "#!") {
+		return source
+	}
+
+	if i := strings.IndexByte(source, '\n'); i >= 0 {
+		return source[i:]
+	}
+	return ""
+}
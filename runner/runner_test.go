@@ -0,0 +1,239 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dominicgaliano/interpreter-demo/object"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{"let x = 1 + 2; x * 2", StatusOK, ""},
+		{"1 + true", StatusError, "ERROR: type mismatch: INTEGER + BOOLEAN\n"},
+		{"let x 5;", StatusError,
+			"parser errors:\n\t1:7: expected next token to be =, got INT instead\n"},
+		{`puts("a", 1); 2`, StatusOK, "a\n1\n"},
+		{"/* setup\n */ let x = 1; // x is one\nputs(x)", StatusOK, "1\n"},
+		{"puts(1) /* unterminated", StatusError,
+			"parser errors:\n\t1:9: no prefix parse function for ILLEGAL found\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		_, status := Run(tt.input, object.NewEnvironment(), &out)
+
+		if status != tt.expectedStatus {
+			t.Errorf("wrong status for %q. got=%d, want=%d",
+				tt.input, status, tt.expectedStatus)
+		}
+
+		if out.String() != tt.expectedOutput {
+			t.Errorf("wrong output for %q. got=%q, want=%q",
+				tt.input, out.String(), tt.expectedOutput)
+		}
+	}
+}
+
+func TestRunExit(t *testing.T) {
+	env := object.NewEnvironment()
+
+	var out bytes.Buffer
+	evaluated, status := Run("let x = 1; exit(2); let x = 3;", env, &out)
+
+	if status != 2 {
+		t.Errorf("wrong status. got=%d, want=2", status)
+	}
+
+	exit, ok := evaluated.(*object.Exit)
+	if !ok || exit.Code != 2 {
+		t.Errorf("result is not exit(2). got=%T (%+v)", evaluated, evaluated)
+	}
+
+	// evaluation stopped at the exit call
+	if x, _ := env.Get("x"); x.(*object.Integer).Value != 1 {
+		t.Errorf("statements after exit were evaluated. x=%s", x.Inspect())
+	}
+
+	if out.String() != "" {
+		t.Errorf("exit produced output. got=%q", out.String())
+	}
+}
+
+func TestRunConcurrently(t *testing.T) {
+	// each run prints to its own output, even while others are running
+	outs := make([]bytes.Buffer, 4)
+
+	var wg sync.WaitGroup
+	for i := range outs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			source := fmt.Sprintf("times(100, fn(_) { puts(%d) })", i)
+			Run(source, newEnvironment(Options{}), &outs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, out := range outs {
+		expected := strings.Repeat(fmt.Sprintf("%d\n", i), 100)
+		if out.String() != expected {
+			t.Errorf("wrong output for run %d. got=%q", i, out.String())
+		}
+	}
+}
+
+func TestEvalString(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{"1 + 2", StatusOK, "3\n"},
+		{"let x = 3;", StatusOK, ""},
+		{"-true", StatusError, "ERROR: unknown operator: -BOOLEAN\n"},
+		{"exit()", StatusOK, ""},
+		{"sum([1, 2, 3])", StatusOK, "6\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		status := EvalString(tt.input, &out)
+
+		if status != tt.expectedStatus {
+			t.Errorf("wrong status for %q. got=%d, want=%d",
+				tt.input, status, tt.expectedStatus)
+		}
+
+		if out.String() != tt.expectedOutput {
+			t.Errorf("wrong output for %q. got=%q, want=%q",
+				tt.input, out.String(), tt.expectedOutput)
+		}
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.monkey")
+	if err := os.WriteFile(path, []byte("let x = 1;\nx + true;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	status := RunFile(path, &out)
+
+	if status != StatusError {
+		t.Errorf("wrong status. got=%d, want=%d", status, StatusError)
+	}
+
+	expected := "ERROR: type mismatch: INTEGER + BOOLEAN\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), expected)
+	}
+}
+
+func TestRunFileShebang(t *testing.T) {
+	tests := []struct {
+		source         string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{"#!/usr/bin/env monkey\nputs(1 + 2)\n", StatusOK, "3\n"},
+		{"#!/usr/bin/env monkey", StatusOK, ""},
+		{"#!/usr/bin/env monkey\r\nputs(1)", StatusOK, "1\n"},
+		// only the first line at the very start of the file is special
+		{"puts(1)\n#!/usr/bin/env monkey\n", StatusError,
+			"parser errors:\n\t2:1: no prefix parse function for ILLEGAL found\n" +
+				"\t2:3: no prefix parse function for / found\n"},
+		{" #!/usr/bin/env monkey\n1", StatusError,
+			"parser errors:\n\t1:2: no prefix parse function for ILLEGAL found\n" +
+				"\t1:4: no prefix parse function for / found\n"},
+		{"#!/usr/bin/env monkey\n1 # 2", StatusError,
+			"parser errors:\n\t2:3: no prefix parse function for ILLEGAL found\n"},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "script.monkey")
+		if err := os.WriteFile(path, []byte(tt.source), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		status := RunFile(path, &out)
+
+		if status != tt.expectedStatus {
+			t.Errorf("wrong status for %q. got=%d, want=%d",
+				tt.source, status, tt.expectedStatus)
+		}
+		if out.String() != tt.expectedOutput {
+			t.Errorf("wrong output for %q. got=%q, want=%q",
+				tt.source, out.String(), tt.expectedOutput)
+		}
+	}
+}
+
+func TestWithoutPrelude(t *testing.T) {
+	var out bytes.Buffer
+	status := EvalStringWithOptions("sum([1, 2, 3])", &out, Options{NoPrelude: true})
+
+	if status != StatusError {
+		t.Errorf("wrong status. got=%d, want=%d", status, StatusError)
+	}
+	if out.String() != "ERROR: identifier not found: sum\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+func TestMaxSourceSize(t *testing.T) {
+	opts := Options{MaxSourceSize: 10}
+
+	var out bytes.Buffer
+	evaluated, status := RunWithOptions("1 + 2 + 3 + 4", object.NewEnvironment(), &out, opts)
+
+	expected := "source exceeds the maximum size of 10 bytes"
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != expected {
+		t.Errorf("wrong result. got=%T (%+v)", evaluated, evaluated)
+	}
+	if status != StatusError {
+		t.Errorf("wrong status. got=%d, want=%d", status, StatusError)
+	}
+	if out.String() != "ERROR: "+expected+"\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+
+	// exactly the maximum is fine
+	out.Reset()
+	_, status = RunWithOptions("1 + 2 + 34", object.NewEnvironment(), &out, opts)
+	if status != StatusOK {
+		t.Errorf("source at the limit rejected. output=%q", out.String())
+	}
+
+	// files are not read past the limit
+	path := filepath.Join(t.TempDir(), "large.monkey")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("1;"), 1000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if status := RunFileWithOptions(path, &out, opts); status != StatusError {
+		t.Errorf("wrong status for large file. got=%d", status)
+	}
+	if out.String() != "ERROR: "+expected+"\n" {
+		t.Errorf("wrong output for large file. got=%q", out.String())
+	}
+
+	// a negative size disables the limit
+	out.Reset()
+	_, status = RunWithOptions("1 + 2 + 3 + 4", object.NewEnvironment(), &out,
+		Options{MaxSourceSize: -1})
+	if status != StatusOK {
+		t.Errorf("source rejected without a limit. output=%q", out.String())
+	}
+}
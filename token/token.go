@@ -5,6 +5,8 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // 1-based line of the token's first character
+	Column  int // 1-based column, in bytes, of the token's first character
 }
 
 const (
@@ -12,8 +14,13 @@ const (
 	EOF     = "EOF"
 
 	// Identifiers + literals
-	IDENT = "IDENT" // add, foobar, x, y, etc...
-	INT   = "INT"   // 123456
+	IDENT  = "IDENT"  // add, foobar, x, y, etc...
+	INT    = "INT"    // 123456
+	FLOAT  = "FLOAT"  // 1.5
+	STRING = "STRING" // "foobar"
+
+	// Only produced when the lexer is asked to keep comments
+	COMMENT = "COMMENT" // // foo, /* foo */
 
 	// Operators
 	ASSIGN   = "="
@@ -22,19 +29,28 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
 	LT       = "<"
 	GT       = ">"
     EQ       = "=="
     NOT_EQ   = "!="
+	ARROW    = "=>"
+	PIPE     = "|>"
+	AND      = "&&"
+	OR       = "||"
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	ELLIPSIS  = "..."
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -44,6 +60,8 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MATCH    = "MATCH"
+	WHILE    = "WHILE"
 )
 
 var keywords = map[string]TokenType{
@@ -54,6 +72,8 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"match":  MATCH,
+	"while":  WHILE,
 }
 
 func LookupIdentifier(ident string) TokenType {
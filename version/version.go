@@ -0,0 +1,40 @@
+// Package version is the single source of truth for the interpreter's
+// release version and the build it was produced from.
+package version
+
+import (
+	"runtime/debug"
+)
+
+// Version is the semantic version of the interpreter.
+const Version = "0.1.0"
+
+// Revision returns the VCS revision the running binary was built from, or an
+// empty string when the build info does not record one (ex. under go test).
+func Revision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			// shorten to the conventional abbreviated hash
+			if len(setting.Value) > 7 {
+				return setting.Value[:7]
+			}
+			return setting.Value
+		}
+	}
+
+	return ""
+}
+
+// String returns the version followed by the build revision when known.
+// Ex. 0.1.0 (a2863d0)
+func String() string {
+	if revision := Revision(); revision != "" {
+		return Version + " (" + revision + ")"
+	}
+	return Version
+}
@@ -0,0 +1,20 @@
+package version
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	s := String()
+
+	if !strings.HasPrefix(s, Version) {
+		t.Fatalf("String() does not start with Version. got=%q, want prefix=%q",
+			s, Version)
+	}
+
+	if revision := Revision(); revision != "" && !strings.Contains(s, revision) {
+		t.Fatalf("String() does not contain Revision(). got=%q, want=%q",
+			s, revision)
+	}
+}